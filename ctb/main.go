@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"git.catbo.net/muravjov/go2023/util"
+	"git.catbo.net/muravjov/go2023/xliff"
 	"github.com/spf13/cobra"
 )
 
@@ -38,19 +39,127 @@ func runCLI() (exitOK bool) {
 	// * md2md
 	var md2html bool
 	var dumpAST bool
+	var unsupported string
+	var keepSource bool
+	var transforms []string
 
 	md2mdCmd := &cobra.Command{
 		Use:   "md2md srcfile|- dstfile|-",
 		Short: "convert markdown to markdown",
 		Run: func(cmd *cobra.Command, args []string) {
-			exitOK = md2md(md2html, dumpAST, args)
+			exitOK = md2md(md2html, dumpAST, unsupported, keepSource, transforms, args)
 		},
 	}
 	md2mdCmd.Flags().BoolVar(&md2html, "md2html", false, "markdown to html instead")
 	md2mdCmd.Flags().BoolVar(&dumpAST, "dumpAST", false, "dump dumps an AST tree structure to stdout")
+	md2mdCmd.Flags().StringVar(&unsupported, "unsupported", "ignore", "ignore | warn | strict: what to do with nodes md2md can't render")
+	md2mdCmd.Flags().BoolVar(&keepSource, "keep-source", true, "copy unchanged blocks from the source as is")
+	md2mdCmd.Flags().StringArrayVar(&transforms, "transform", nil,
+		"link-prefix:FROM=TO | shift-headings:N | replace-text:FROM=TO | drop-html-comments, may be repeated")
 
 	rootCmd.AddCommand(md2mdCmd)
 
+	// * xliff
+	xliffCmd := &cobra.Command{
+		Use:   "xliff",
+		Short: "exchange translations with CAT tools via XLIFF",
+	}
+
+	var from, to, xliffVersion, srx string
+	xliffExtractCmd := &cobra.Command{
+		Use:   "extract srcfile|- dstfile|-",
+		Short: "extract segments of markdown to XLIFF",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = xliffExtract(from, to, xliffVersion, srx, args)
+		},
+	}
+	xliffExtractCmd.Flags().StringVar(&from, "from", "", "source language, like en")
+	xliffExtractCmd.MarkFlagRequired("from")
+	xliffExtractCmd.Flags().StringVar(&to, "to", "", "target language, like ru")
+	xliffExtractCmd.Flags().StringVar(&xliffVersion, "version", string(xliff.Version20), "2.0 | 1.2")
+	xliffExtractCmd.Flags().StringVar(&srx, "srx", "", "SRX file of sentence rules, none to keep segments whole; built-in rules by default")
+
+	xliffMergeCmd := &cobra.Command{
+		Use:   "merge srcfile|- xlifffile dstfile|-",
+		Short: "put translations of XLIFF into markdown",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = xliffMerge(args)
+		},
+	}
+
+	xliffCmd.AddCommand(xliffExtractCmd, xliffMergeCmd)
+	rootCmd.AddCommand(xliffCmd)
+
+	// * po
+	poCmd := &cobra.Command{
+		Use:   "po",
+		Short: "translate markdown via gettext PO files, po4a-style",
+	}
+
+	var poFrom, poLang, poSrx string
+	poUpdateCmd := &cobra.Command{
+		Use:   "update srcfile|- pofile",
+		Short: "create or update a PO file with segments of markdown",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = poUpdate(poFrom, poLang, poSrx, args)
+		},
+	}
+	poUpdateCmd.Flags().StringVar(&poLang, "lang", "", "language of translations, like ru, for the PO header")
+
+	poApplyCmd := &cobra.Command{
+		Use:   "apply srcfile|- pofile dstfile|-",
+		Short: "render markdown translated with a PO file",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = poApply(poFrom, poSrx, args)
+		},
+	}
+
+	for _, cmd := range []*cobra.Command{poUpdateCmd, poApplyCmd} {
+		cmd.Flags().StringVar(&poFrom, "from", "en", "source language, for sentence rules")
+		cmd.Flags().StringVar(&poSrx, "srx", "", "SRX file of sentence rules, none to keep segments whole; built-in rules by default")
+	}
+
+	poCmd.AddCommand(poUpdateCmd, poApplyCmd)
+	rootCmd.AddCommand(poCmd)
+
+	// * tm
+	tmCmd := &cobra.Command{
+		Use:   "tm",
+		Short: "manage a translation memory of a project",
+	}
+
+	var tmUser string
+	tmImportCmd := &cobra.Command{
+		Use:   "import tmfile tmxfile",
+		Short: "import TMX into a translation memory, it is created if there is none",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = tmImport(tmUser, args)
+		},
+	}
+	tmImportCmd.Flags().StringVar(&tmUser, "user", "", "author of units without creationid/changeid")
+
+	var tmFrom, tmTo string
+	tmExportCmd := &cobra.Command{
+		Use:   "export tmfile dstfile|-",
+		Short: "export a translation memory to TMX 1.4b",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = tmExport(tmFrom, tmTo, args)
+		},
+	}
+	tmExportCmd.Flags().StringVar(&tmFrom, "from", "", "source language, all by default")
+	tmExportCmd.Flags().StringVar(&tmTo, "to", "", "target language, all by default")
+
+	tmStatsCmd := &cobra.Command{
+		Use:   "stats tmfile",
+		Short: "print statistics of a translation memory by language pairs",
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOK = tmStats(args)
+		},
+	}
+
+	tmCmd.AddCommand(tmImportCmd, tmExportCmd, tmStatsCmd)
+	rootCmd.AddCommand(tmCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		util.Errorf("CLI error: %s", err)
 		exitOK = false
@@ -1,16 +1,71 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	"git.catbo.net/muravjov/go2023/markdown"
 	"git.catbo.net/muravjov/go2023/util"
 )
 
-func md2md(md2html bool, dumpAST bool, args []string) bool {
+var unsupportedNodes = map[string]markdown.UnsupportedNodes{
+	"ignore": markdown.UnsupportedNodesIgnore,
+	"warn":   markdown.UnsupportedNodesWarn,
+	"strict": markdown.UnsupportedNodesStrict,
+}
+
+// parseTransform parses --transform value like "link-prefix:/docs/=/v2/docs/"
+func parseTransform(spec string) (markdown.Transformer, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	from, to, pair := strings.Cut(arg, "=")
+
+	switch name {
+	case "link-prefix":
+		if !pair {
+			return nil, fmt.Errorf("%s: FROM=TO expected", spec)
+		}
+		return markdown.LinkPrefix(from, to), nil
+	case "shift-headings":
+		delta, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", spec, err)
+		}
+		return markdown.ShiftHeadings(delta), nil
+	case "replace-text":
+		if !pair || from == "" {
+			return nil, fmt.Errorf("%s: FROM=TO expected", spec)
+		}
+		return markdown.ReplaceText(from, to), nil
+	case "drop-html-comments":
+		return markdown.DropHTMLComments(), nil
+	}
+	return nil, fmt.Errorf("unknown transform: %s", spec)
+}
+
+func md2md(md2html bool, dumpAST bool, unsupported string, keepSource bool, transforms []string, args []string) bool {
 	if len(args) != 2 {
 		util.Errorf("html2markdown: strictly 2 arguments required")
 		return false
 	}
 
+	unsupportedNodes, ok := unsupportedNodes[unsupported]
+	if !ok {
+		util.Errorf("md2md: unknown --unsupported value: %s", unsupported)
+		return false
+	}
+
+	var transformers []markdown.Transformer
+	for _, spec := range transforms {
+		transformer, err := parseTransform(spec)
+		if err != nil {
+			util.Errorf("md2md: %v", err)
+			return false
+		}
+		transformers = append(transformers, transformer)
+	}
+
 	srcFilename, dstFilename := args[0], args[1]
 
 	dat, res := openSrc(srcFilename)
@@ -24,7 +79,26 @@ func md2md(md2html bool, dumpAST bool, args []string) bool {
 	}
 	defer dstF.Close()
 
-	markdown.Convert(dat, dstF, !md2html, dumpAST, false)
+	opts := []markdown.ConvertOption{
+		markdown.WithMarkdownOptions(
+			markdown.WithUnsupportedNodes(unsupportedNodes),
+			markdown.WithWarn(func(err error) {
+				util.Infof("md2md: warning: %v", err)
+			}),
+			markdown.WithKeepSource(keepSource),
+		),
+		markdown.WithTransformers(transformers...),
+	}
+	if md2html {
+		opts = append(opts, markdown.WithFormat(markdown.FormatHTML))
+	}
+	if dumpAST {
+		opts = append(opts, markdown.WithDump(os.Stdout))
+	}
+	if err := markdown.Convert(dat, dstF, opts...); err != nil {
+		util.Errorf("md2md: %s: %v", srcFilename, err)
+		return false
+	}
 
 	return true
 }
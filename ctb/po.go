@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+	"git.catbo.net/muravjov/go2023/po"
+	"git.catbo.net/muravjov/go2023/util"
+)
+
+// readPO reads a PO file, nil if it doesn't exist and mayNotExist
+func readPO(poFilename string, mayNotExist bool) (*po.File, bool) {
+	poF, err := os.Open(poFilename)
+	if mayNotExist && errors.Is(err, fs.ErrNotExist) {
+		return nil, true
+	}
+	if err != nil {
+		util.Errorf("error while opening file %v: %v", poFilename, err)
+		return nil, false
+	}
+	defer poF.Close()
+
+	f, err := po.Read(poF)
+	if err != nil {
+		util.Errorf("%s: %v", poFilename, err)
+		return nil, false
+	}
+	return f, true
+}
+
+func poUpdate(from, lang, srx string, args []string) bool {
+	if len(args) != 2 {
+		util.Errorf("po update: strictly 2 arguments required")
+		return false
+	}
+
+	srcFilename, poFilename := args[0], args[1]
+
+	dat, res := openSrc(srcFilename)
+	if !res {
+		return res
+	}
+
+	segments, res := extractSegments(dat, from, srx)
+	if !res {
+		return res
+	}
+
+	f, res := readPO(poFilename, true)
+	if !res {
+		return res
+	}
+	f = po.Update(f, srcFilename, lang, segments)
+
+	dstF, res := openDst(poFilename)
+	if !res {
+		return res
+	}
+	defer dstF.Close()
+
+	if err := po.Write(dstF, f); err != nil {
+		util.Errorf("po update: %v", err)
+		return false
+	}
+
+	return true
+}
+
+func poApply(from, srx string, args []string) bool {
+	if len(args) != 3 {
+		util.Errorf("po apply: strictly 3 arguments required")
+		return false
+	}
+
+	srcFilename, poFilename, dstFilename := args[0], args[1], args[2]
+
+	dat, res := openSrc(srcFilename)
+	if !res {
+		return res
+	}
+
+	segments, res := extractSegments(dat, from, srx)
+	if !res {
+		return res
+	}
+
+	f, res := readPO(poFilename, false)
+	if !res {
+		return res
+	}
+
+	dstF, res := openDst(dstFilename)
+	if !res {
+		return res
+	}
+	defer dstF.Close()
+
+	opts := markdown.WithMarkdownOptions(markdown.WithKeepSource(true))
+	if err := markdown.Translate(dat, dstF, f.Translations(segments), opts); err != nil {
+		util.Errorf("po apply: %s: %v", poFilename, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.catbo.net/muravjov/go2023/tm"
+	"git.catbo.net/muravjov/go2023/util"
+)
+
+func openTM(tmFilename string) (*tm.Memory, bool) {
+	m, err := tm.Open(tmFilename)
+	if err != nil {
+		util.Errorf("error while reading translation memory %v: %v", tmFilename, err)
+		return nil, false
+	}
+	return m, true
+}
+
+func tmImport(user string, args []string) bool {
+	if len(args) != 2 {
+		util.Errorf("tm import: strictly 2 arguments required")
+		return false
+	}
+
+	tmFilename, tmxFilename := args[0], args[1]
+
+	m, res := openTM(tmFilename)
+	if !res {
+		return res
+	}
+
+	tmxF, err := os.Open(tmxFilename)
+	if err != nil {
+		util.Errorf("error while opening file %v: %v", tmxFilename, err)
+		return false
+	}
+	defer tmxF.Close()
+
+	stats, err := m.ImportTMX(tmxF, user)
+	if err != nil {
+		util.Errorf("%s: %v", tmxFilename, err)
+		return false
+	}
+	if err := m.Save(); err != nil {
+		util.Errorf("tm import: %v", err)
+		return false
+	}
+
+	util.Infof("tm import: %d added, %d changed, %d skipped, %d older kept",
+		stats.Added, stats.Changed, stats.Skipped, stats.Older)
+	return true
+}
+
+func tmExport(from, to string, args []string) bool {
+	if len(args) != 2 {
+		util.Errorf("tm export: strictly 2 arguments required")
+		return false
+	}
+
+	tmFilename, dstFilename := args[0], args[1]
+
+	m, res := openTM(tmFilename)
+	if !res {
+		return res
+	}
+
+	dstF, res := openDst(dstFilename)
+	if !res {
+		return res
+	}
+	defer dstF.Close()
+
+	if err := m.ExportTMX(dstF, from, to); err != nil {
+		util.Errorf("tm export: %v", err)
+		return false
+	}
+
+	return true
+}
+
+func tmStats(args []string) bool {
+	if len(args) != 1 {
+		util.Errorf("tm stats: strictly 1 argument required")
+		return false
+	}
+
+	m, res := openTM(args[0])
+	if !res {
+		return res
+	}
+
+	for _, s := range m.Stats() {
+		fmt.Printf("%s -> %s: %d units, %d source words, %d target words, last changed %s\n",
+			s.SrcLang, s.TrgLang, s.Units, s.SourceWords, s.TargetWords, s.LastChanged.Format("2006-01-02 15:04:05"))
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+	"git.catbo.net/muravjov/go2023/util"
+	"git.catbo.net/muravjov/go2023/xliff"
+)
+
+// sentenceRules returns rules of the --srx file, the default ones if it is empty
+func sentenceRules(srxFilename string) (*markdown.SentenceRules, bool) {
+	if srxFilename == "" {
+		return markdown.DefaultSentenceRules(), true
+	}
+
+	f, err := os.Open(srxFilename)
+	if err != nil {
+		util.Errorf("error while opening file %v: %v", srxFilename, err)
+		return nil, false
+	}
+	defer f.Close()
+
+	rules, err := markdown.LoadSentenceRules(f)
+	if err != nil {
+		util.Errorf("%s: %v", srxFilename, err)
+		return nil, false
+	}
+	return rules, true
+}
+
+// extractSegments returns segments of src, split into sentences unless srx is "none"
+func extractSegments(src []byte, lang string, srx string) ([]markdown.Segment, bool) {
+	segments := markdown.Segments(src)
+	if srx == "none" {
+		return segments, true
+	}
+
+	rules, ok := sentenceRules(srx)
+	if !ok {
+		return nil, false
+	}
+	return rules.SplitSegments(lang, segments), true
+}
+
+func xliffExtract(from, to, version, srx string, args []string) bool {
+	if len(args) != 2 {
+		util.Errorf("xliff extract: strictly 2 arguments required")
+		return false
+	}
+
+	srcFilename, dstFilename := args[0], args[1]
+
+	dat, res := openSrc(srcFilename)
+	if !res {
+		return res
+	}
+
+	segments, res := extractSegments(dat, from, srx)
+	if !res {
+		return res
+	}
+
+	dstF, res := openDst(dstFilename)
+	if !res {
+		return res
+	}
+	defer dstF.Close()
+
+	f := &xliff.File{
+		Original: srcFilename,
+		SrcLang:  from,
+		TrgLang:  to,
+		Segments: segments,
+	}
+	if err := xliff.Write(dstF, f, xliff.Version(version)); err != nil {
+		util.Errorf("xliff extract: %v", err)
+		return false
+	}
+
+	return true
+}
+
+func xliffMerge(args []string) bool {
+	if len(args) != 3 {
+		util.Errorf("xliff merge: strictly 3 arguments required")
+		return false
+	}
+
+	srcFilename, xliffFilename, dstFilename := args[0], args[1], args[2]
+
+	dat, res := openSrc(srcFilename)
+	if !res {
+		return res
+	}
+
+	xliffF, err := os.Open(xliffFilename)
+	if err != nil {
+		util.Errorf("error while opening file %v: %v", xliffFilename, err)
+		return false
+	}
+	defer xliffF.Close()
+
+	f, err := xliff.Read(xliffF)
+	if err != nil {
+		util.Errorf("%s: %v", xliffFilename, err)
+		return false
+	}
+
+	dstF, res := openDst(dstFilename)
+	if !res {
+		return res
+	}
+	defer dstF.Close()
+
+	opts := markdown.WithMarkdownOptions(markdown.WithKeepSource(true))
+	if err := markdown.Translate(dat, dstF, f.Translations(), opts); err != nil {
+		util.Errorf("xliff merge: %s: %v", xliffFilename, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,50 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+)
+
+// md2md keeps details of nodes, like markers they have been written with or their source rows,
+// in a table of their document instead of Node.Attributes(), which the html renderer and others
+// write out.
+
+// nodeAttributesKey is the key of the table in the document meta
+const nodeAttributesKey = "md2md-node-attributes"
+
+type nodeAttributes map[ast.Node]map[string]any
+
+// attributesOf returns the table of the document of n, nil if n is out of a document
+func attributesOf(n ast.Node, create bool) nodeAttributes {
+	for n.Parent() != nil {
+		n = n.Parent()
+	}
+	doc, ok := n.(*ast.Document)
+	if !ok {
+		return nil
+	}
+	if attrs, ok := doc.Meta()[nodeAttributesKey].(nodeAttributes); ok || !create {
+		return attrs
+	}
+	attrs := nodeAttributes{}
+	doc.AddMeta(nodeAttributesKey, attrs)
+	return attrs
+}
+
+// setAttribute sets an md2md attribute of n; in is a node of the document: n itself, or
+// the parent of a node being parsed, which isn't in the document yet
+func setAttribute(in, n ast.Node, name string, value any) {
+	attrs := attributesOf(in, true)
+	if attrs == nil {
+		return
+	}
+	if attrs[n] == nil {
+		attrs[n] = map[string]any{}
+	}
+	attrs[n][name] = value
+}
+
+// attribute returns an md2md attribute of n
+func attribute(n ast.Node, name string) (any, bool) {
+	v, ok := attributesOf(n, false)[n][name]
+	return v, ok
+}
@@ -0,0 +1,127 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// minimal code fence is 3 chars long, like "```"
+const minCodeFenceLength = 3
+
+// codeFence is the opening fence of a fenced code block as written in the source
+type codeFence struct {
+	char   byte
+	length int
+	indent int
+}
+
+const codeFenceAttr = "md2md-code-fence"
+
+// fencedCodeBlockParser wraps parser.NewFencedCodeBlockParser() to save the opening fence,
+// ast.FencedCodeBlock keeps just the info string
+type fencedCodeBlockParser struct {
+	parser.BlockParser
+}
+
+func newFencedCodeBlockParser() parser.BlockParser {
+	return &fencedCodeBlockParser{
+		BlockParser: parser.NewFencedCodeBlockParser(),
+	}
+}
+
+func (b *fencedCodeBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	node, state := b.BlockParser.Open(parent, reader, pc)
+	if node != nil {
+		fence := &codeFence{
+			char:   line[pos],
+			indent: pos,
+		}
+		for i := pos; i < len(line) && line[i] == fence.char; i++ {
+			fence.length++
+		}
+		setAttribute(parent, node, codeFenceAttr, fence)
+	}
+	return node, state
+}
+
+// closesCodeFence tells if the line would be taken for the closing fence
+func closesCodeFence(line []byte, char byte, length int) bool {
+	w, pos := util.IndentWidth(line, 0)
+	if w >= 4 {
+		return false
+	}
+	i := pos
+	for i < len(line) && line[i] == char {
+		i++
+	}
+	return i-pos >= length && util.IsBlank(line[i:])
+}
+
+// codeFence returns the source fence, made longer if the content has been changed
+// and now has a line which would close it
+func (r *nodeRenderer) codeFence(source []byte, n *ast.FencedCodeBlock) codeFence {
+	fence := codeFence{char: '`', length: minCodeFenceLength}
+	if v, ok := attribute(n, codeFenceAttr); ok {
+		fence = *v.(*codeFence)
+	}
+
+	if fence.char == '`' && n.Info != nil && bytes.IndexByte(n.Info.Segment.Value(source), '`') >= 0 {
+		// backticks aren't allowed in the info string of a backtick fence
+		fence.char = '~'
+	}
+
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		line := segment.Value(source)
+		for closesCodeFence(line, fence.char, fence.length) {
+			fence.length++
+		}
+	}
+	return fence
+}
+
+func (r *nodeRenderer) renderFencedCodeBlock(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.FencedCodeBlock)
+
+	fence := r.codeFence(source, n)
+	indent := strings.Repeat(" ", fence.indent)
+	marker := strings.Repeat(string(fence.char), fence.length)
+
+	if !entering {
+		_, _ = w.WriteString(indent + marker)
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(indent + marker)
+	if n.Info != nil {
+		// the whole info string, not only the language
+		if start := n.Info.Segment.Start; start > 0 && isSpaceOrTab(source[start-1]) {
+			_ = w.WriteByte(' ')
+		}
+		_, _ = w.Write(n.Info.Segment.Value(source))
+	}
+	_ = w.WriteByte('\n')
+	r.context.Pad(w)
+
+	// the parser strips up to fence indentation from content lines, so it's put back
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		b := line.Value(source)
+		if !util.IsBlank(b) {
+			_, _ = w.WriteString(indent)
+		}
+		_, _ = w.Write(b)
+		r.context.Pad(w)
+	}
+	return ast.WalkContinue, nil
+}
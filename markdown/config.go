@@ -0,0 +1,93 @@
+package markdown
+
+// Config holds md2md rendering options.
+type Config struct {
+	// TablePadding pads table cells with spaces so that columns line up
+	TablePadding bool
+
+	// ListNumbering is how items of ordered lists are numbered
+	ListNumbering ListNumbering
+
+	// HeadingStyle is ATX or setext style of headings
+	HeadingStyle HeadingStyle
+
+	// ThematicBreak is the line to write thematic breaks with, like "---";
+	// empty one keeps the source line
+	ThematicBreak string
+
+	// HardLineBreakStyle is \ or 2 spaces at the end of line
+	HardLineBreakStyle HardLineBreakStyle
+
+	// UnsupportedNodes is what to do with nodes of kinds without a NodeRendererFunc
+	UnsupportedNodes UnsupportedNodes
+
+	// Warn is given a RenderError for every unsupported node with UnsupportedNodesWarn
+	Warn func(error)
+
+	// KeepSource copies blocks nobody has changed from the source as is, containers with changes
+	// within keep their source prefixes, see MarkChanged; other options apply to changed blocks only
+	KeepSource bool
+}
+
+func NewConfig() Config {
+	return Config{}
+}
+
+// Option is a functional option for md2md rendering.
+type Option func(*Config)
+
+// WithTablePadding pads table cells with spaces so that columns line up.
+func WithTablePadding(v bool) Option {
+	return func(c *Config) {
+		c.TablePadding = v
+	}
+}
+
+// WithListNumbering sets how items of ordered lists are numbered.
+func WithListNumbering(v ListNumbering) Option {
+	return func(c *Config) {
+		c.ListNumbering = v
+	}
+}
+
+// WithHeadingStyle sets how headings are written.
+func WithHeadingStyle(v HeadingStyle) Option {
+	return func(c *Config) {
+		c.HeadingStyle = v
+	}
+}
+
+// WithThematicBreak sets the line to write thematic breaks with, like "---".
+func WithThematicBreak(v string) Option {
+	return func(c *Config) {
+		c.ThematicBreak = v
+	}
+}
+
+// WithHardLineBreakStyle sets how hard line breaks are written.
+func WithHardLineBreakStyle(v HardLineBreakStyle) Option {
+	return func(c *Config) {
+		c.HardLineBreakStyle = v
+	}
+}
+
+// WithUnsupportedNodes sets what to do with nodes md2md can't render.
+func WithUnsupportedNodes(v UnsupportedNodes) Option {
+	return func(c *Config) {
+		c.UnsupportedNodes = v
+	}
+}
+
+// WithWarn sets the func warnings of UnsupportedNodesWarn are given to.
+func WithWarn(v func(error)) Option {
+	return func(c *Config) {
+		c.Warn = v
+	}
+}
+
+// WithKeepSource copies unchanged blocks from the source as is.
+func WithKeepSource(v bool) Option {
+	return func(c *Config) {
+		c.KeepSource = v
+	}
+}
@@ -1,42 +1,124 @@
 package markdown
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"strings"
 
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/util"
 )
 
+// Context is shared by node renderers of a Renderer. It doesn't change while rendering:
+// the state of a Render call goes with its writer, so one Renderer may render
+// several documents at the same time.
 type Context struct {
-	PaddingStack []string
+	// Config is shared by node renderers of CommonMark and of extensions
+	Config Config
 
-	counter int
 	verbose bool
+
+	render func(w util.BufWriter, source []byte, n ast.Node) error
 }
 
 func NewContext(verbose bool) *Context {
 	r := &Context{
+		Config:  NewConfig(),
 		verbose: verbose,
 	}
 
 	return r
 }
 
-func (c *Context) PushStack(pad string) {
-	c.PaddingStack = append(c.PaddingStack, pad)
+// renderState is the state of a single Render call
+type renderState struct {
+	paddingStack []string
+	counter      int
+
+	// nodes without NodeRendererFunc, for UnsupportedNodesStrict
+	unsupported []*RenderError
+
+	// blocks to be rendered with Config.KeepSource, others are copied from the source
+	changed map[ast.Node]bool
+
+	// blocks which keep their source with changes within, see Renderer.splicesSource
+	spliced map[ast.Node]bool
+}
+
+// renderWriter carries the render state through the walk to node renderers
+type renderWriter struct {
+	util.BufWriter
+
+	state *renderState
+}
+
+func newRenderWriter(w util.BufWriter, state *renderState) util.BufWriter {
+	return &renderWriter{
+		BufWriter: w,
+		state:     state,
+	}
+}
+
+// state returns the render state of w; a writer out of Render gets an empty one
+func (c *Context) state(w util.BufWriter) *renderState {
+	if rw, ok := w.(*renderWriter); ok {
+		return rw.state
+	}
+	return &renderState{}
 }
 
-func (c *Context) PopStack() {
-	c.PaddingStack = c.PaddingStack[:len(c.PaddingStack)-1]
+// PushStack adds the padding written after newlines, like "> " of a blockquote.
+func (c *Context) PushStack(w util.BufWriter, pad string) {
+	state := c.state(w)
+	state.paddingStack = append(state.paddingStack, pad)
+}
+
+// PopStack removes the last padding added with PushStack.
+func (c *Context) PopStack(w util.BufWriter) {
+	state := c.state(w)
+	if l := len(state.paddingStack); l > 0 {
+		state.paddingStack = state.paddingStack[:l-1]
+	}
+}
+
+// PaddingStack returns paddings of the render w belongs to.
+func (c *Context) PaddingStack(w util.BufWriter) []string {
+	return c.state(w).paddingStack
 }
 
 func (c *Context) Pad(w util.BufWriter) {
+	state := c.state(w)
 	if c.verbose {
-		_, _ = w.WriteString(fmt.Sprintf("%v", c.counter))
-		c.counter++
+		_, _ = w.WriteString(fmt.Sprintf("%v", state.counter))
+		state.counter++
+	}
+
+	if stack := state.paddingStack; len(stack) > 0 {
+		_, _ = w.WriteString(c.padding(w))
 	}
+}
+
+func (c *Context) padding(w util.BufWriter) string {
+	return strings.Join(c.state(w).paddingStack, "")
+}
 
-	if stack := c.PaddingStack; len(stack) > 0 {
-		_, _ = w.WriteString(strings.Join(stack, ""))
+// RenderChildren renders children of n into w, e.g. to measure or post-process them
+// before writing to the output
+func (c *Context) RenderChildren(w util.BufWriter, source []byte, n ast.Node) error {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if err := c.render(w, source, child); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// RenderToString renders with f into a string instead of w, within the same render.
+func (c *Context) RenderToString(w util.BufWriter, f func(w util.BufWriter) error) (string, error) {
+	var buf bytes.Buffer
+	bw := bufio.NewWriter(&buf)
+	err := f(newRenderWriter(bw, c.state(w)))
+	_ = bw.Flush()
+	return buf.String(), err
 }
@@ -7,59 +7,108 @@ import (
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
-func Convert(source []byte, writer io.Writer, md2md bool, dump bool, verbosePadding bool) error {
-	options := []goldmark.Option{
+func newMarkdown(o Options) goldmark.Markdown {
+	var options []goldmark.Option
+	if o.Format == FormatMarkdown {
+		// it goes first because goldmark.WithParserOptions() is applied to the current parser
+		options = append(options, goldmark.WithParser(NewParser()))
+	}
+
+	var protocols [][]byte
+	for _, p := range o.LinkifyProtocols {
+		protocols = append(protocols, []byte(p))
+	}
+
+	options = append(options, []goldmark.Option{
 		goldmark.WithParserOptions(
 			parser.WithHeadingAttribute(),
 		),
+		goldmark.WithExtensions(
+			// it goes first because extensions add their markdown node renderers to it
+			mdExtensions(o)...,
+		),
 		goldmark.WithExtensions(
 			// we don't need extension.Typographer for md2md because we want to keep source text without substitutions
 			// like don't => don’t
 			//extension.NewTypographer(),
 			extension.NewLinkify(
-				extension.WithLinkifyAllowedProtocols([][]byte{[]byte("http"), []byte("https")}),
+				extension.WithLinkifyAllowedProtocols(protocols),
 				extension.WithLinkifyEmailRegexp(regexp.MustCompile(`[^\x00-\x{10FFFF}]`)), // impossible
 			),
-			extension.DefinitionList,
-			extension.NewTable(),
+			DefinitionList,
+			Table,
+			Strikethrough,
+			TaskList,
+			Footnote,
+			FrontMatterExtension,
 		),
-	}
+		goldmark.WithExtensions(o.Extensions...),
+		goldmark.WithParserOptions(o.ParserOptions...),
+		goldmark.WithParserOptions(parser.WithASTTransformers(astTransformers(o.Transformers)...)),
+	}...)
 
-	if md2md {
-		context := NewContext(verbosePadding)
+	switch o.Format {
+	case FormatMarkdown:
+		// see mdExtensions()
+	case FormatText:
+		nodeRenderers := append([]util.PrioritizedValue{
+			util.Prioritized(newTextNodeRenderer(), TextNodeRendererPriority),
+		}, o.GoldmarkNodeRenderers...)
 
 		options = append(options,
-			// we must overwrite default renderer (which is to html)
-			goldmark.WithRenderer(NewRenderer(context, util.Prioritized(
-				NewNodeRenderer(context),
-				400,
-			))),
+			goldmark.WithRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(nodeRenderers...))),
 		)
-	} else {
+	default:
 		options = append(options, []goldmark.Option{
 			goldmark.WithParserOptions(
 				parser.WithAutoHeadingID(),
 				parser.WithASTTransformers(util.Prioritized(mdTransformFunc(mdLink), 1)),
 			),
-			goldmark.WithRendererOptions(html.WithUnsafe()),
+			goldmark.WithRendererOptions(
+				html.WithUnsafe(),
+				renderer.WithNodeRenderers(o.GoldmarkNodeRenderers...),
+			),
 			goldmark.WithExtensions(
 				extension.NewTypographer(),
 			),
 		}...)
 	}
 
-	md := goldmark.New(options...)
+	return goldmark.New(options...)
+}
+
+// mdExtensions returns MD2MD which overwrites the default renderer (which is to html) for FormatMarkdown
+func mdExtensions(o Options) []goldmark.Extender {
+	if o.Format != FormatMarkdown {
+		return nil
+	}
+	return []goldmark.Extender{
+		&MD2MD{
+			Options:        o.MarkdownOptions,
+			NodeRenderers:  o.NodeRenderers,
+			VerbosePadding: o.VerbosePadding,
+		},
+	}
+}
+
+// Convert converts markdown source to markdown, html or plain text, see ConvertOption.
+func Convert(source []byte, writer io.Writer, opts ...ConvertOption) error {
+	o := NewOptions(opts...)
+	md := newMarkdown(o)
 
 	reader := text.NewReader(source)
 	doc := md.Parser().Parse(reader)
 
-	if dump {
-		doc.Dump(reader.Source(), 0)
+	if o.Dump != nil {
+		if err := Dump(o.Dump, doc, reader.Source()); err != nil {
+			return err
+		}
 	}
 
 	return md.Renderer().Render(writer, source, doc)
@@ -0,0 +1,105 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+func convert(t *testing.T, src string, opts ...ConvertOption) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	assert.NoError(t, Convert([]byte(src), &buf, opts...))
+	return buf.String()
+}
+
+func TestConvertFormats(t *testing.T) {
+	src := "---\ntitle: Hello\n---\n# Heading\n\nSome *text* with [a link](/url) and `code`\\\nbreak &amp; \\*\n\n- one\n- [x] two\n\n| a | b |\n| --- | --- |\n| c | d |\n\n```\ncode block\n```\n\n<div>html</div>\n"
+
+	assert.Equal(t, src, convert(t, src))
+
+	assert.Equal(t, "<h1 id=\"heading\">Heading</h1>\n", convert(t, "# Heading\n", WithFormat(FormatHTML)))
+
+	assert.Equal(t, "Heading\n\nSome text with a link and code\nbreak & *\n\none\n[x] two\n\na\tb\nc\td\n\ncode block",
+		convert(t, src, WithFormat(FormatText)))
+}
+
+func TestConvertLinkifyProtocols(t *testing.T) {
+	src := "ftp://example.com and https://example.com"
+
+	assert.Equal(t, "<p>ftp://example.com and <a href=\"https://example.com\" rel=\"noreferrer\" target=\"_blank\">https://example.com</a></p>\n",
+		convert(t, src, WithFormat(FormatHTML)))
+	assert.Equal(t, "<p><a href=\"ftp://example.com\" rel=\"noreferrer\" target=\"_blank\">ftp://example.com</a> and https://example.com</p>\n",
+		convert(t, src, WithFormat(FormatHTML), WithLinkifyProtocols("ftp")))
+}
+
+type upperCodeSpanRenderer struct {
+}
+
+func (r *upperCodeSpanRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindCodeSpan, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString("`" + string(bytes.ToUpper(n.Text(source))) + "`")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func TestConvertNodeRenderer(t *testing.T) {
+	src := "a `code` span"
+
+	assert.Equal(t, "a `CODE` span", convert(t, src, WithNodeRenderer(&upperCodeSpanRenderer{}, MarkdownNodeRendererPriority-1)))
+
+	// the default one goes first
+	assert.Equal(t, src, convert(t, src, WithNodeRenderer(&upperCodeSpanRenderer{}, MarkdownNodeRendererPriority+1)))
+}
+
+type kbdCodeSpanRenderer struct {
+}
+
+func (r *kbdCodeSpanRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindCodeSpan, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.WriteString("<kbd>" + string(n.Text(source)) + "</kbd>")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func TestConvertGoldmarkNodeRenderer(t *testing.T) {
+	src := "a `code` span"
+	opts := []ConvertOption{
+		WithNodeRenderer(&upperCodeSpanRenderer{}, 0),
+		WithGoldmarkNodeRenderer(&kbdCodeSpanRenderer{}, TextNodeRendererPriority-1),
+	}
+
+	assert.Equal(t, "<p>a <kbd>code</kbd> span</p>\n", convert(t, src, append(opts, WithFormat(FormatHTML))...))
+	assert.Equal(t, "a <kbd>code</kbd> span", convert(t, src, append(opts, WithFormat(FormatText))...))
+	assert.Equal(t, "a `CODE` span", convert(t, src, opts...))
+}
+
+func TestConvertDump(t *testing.T) {
+	var dump bytes.Buffer
+	assert.Equal(t, "# Heading {#h}\n\ntext", convert(t, "# Heading {#h}\n\ntext", WithDump(&dump)))
+	assert.Equal(t, `Document {
+    Heading {
+        RawText: "Heading "
+        HasBlankPreviousLines: true
+        Level: 1
+        id: h
+        Text: "Heading"
+        Text: ""
+    }
+    Paragraph {
+        RawText: "text"
+        HasBlankPreviousLines: true
+        Text: "text"
+    }
+}
+`, dump.String())
+}
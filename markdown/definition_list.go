@@ -0,0 +1,68 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+type definitionListNodeRenderer struct {
+	*nodeRenderer
+}
+
+func newDefinitionListNodeRenderer(context *Context) NodeRenderer {
+	return &definitionListNodeRenderer{newNodeRenderer(context)}
+}
+
+func (r *definitionListNodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(east.KindDefinitionList, r.renderDefinitionList)
+	reg.Register(east.KindDefinitionTerm, r.renderDefinitionTerm)
+	reg.Register(east.KindDefinitionDescription, r.renderDefinitionDescription)
+}
+
+// definition list is PHP Markdown Extra syntax:
+//
+//	Term
+//	: description
+//
+//	  description para 2
+//
+// lines of a description after ": " are padded to its offset, like in list items
+const definitionDescriptionPrefix = ": "
+
+func (r *nodeRenderer) renderDefinitionList(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderDefinitionTerm(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderDefinitionDescription(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(definitionDescriptionPrefix)
+
+		r.context.PushStack(w, "  ")
+	} else {
+		r.context.PopStack(w)
+	}
+	return ast.WalkContinue, nil
+}
+
+// definitionListItemSeparator returns the separator after n, a term or a description;
+// terms of an item go line by line, tight descriptions go right after the previous line
+func definitionListItemSeparator(n ast.Node) string {
+	if n.Kind() == east.KindDefinitionTerm {
+		if _, ok := n.NextSibling().(*east.DefinitionTerm); ok {
+			return "\n"
+		}
+	}
+
+	if desc, ok := n.NextSibling().(*east.DefinitionDescription); ok && desc.IsTight {
+		return "\n"
+	}
+	return "\n\n"
+}
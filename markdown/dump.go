@@ -0,0 +1,117 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// Dump writes the AST to w the way ast.Node.Dump() writes to stdout.
+func Dump(w io.Writer, n ast.Node, source []byte) error {
+	level := 0
+	return ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if _, ok := n.(*ast.Text); ok {
+				return ast.WalkContinue, nil
+			}
+			level--
+			_, err := fmt.Fprintf(w, "%s}\n", strings.Repeat("    ", level))
+			return ast.WalkContinue, err
+		}
+
+		var b strings.Builder
+		dumpNode(&b, n, source, level)
+		if _, ok := n.(*ast.Text); !ok {
+			level++
+		}
+		_, err := io.WriteString(w, b.String())
+		return ast.WalkContinue, err
+	})
+}
+
+// dumpFields are the fields ast.Node.Dump() shows for node types md2md deals with
+func dumpFields(n ast.Node, source []byte) map[string]string {
+	switch n := n.(type) {
+	case *ast.Heading:
+		return map[string]string{"Level": fmt.Sprint(n.Level)}
+	case *ast.List:
+		return map[string]string{
+			"Ordered": fmt.Sprint(n.IsOrdered()),
+			"Marker":  string(n.Marker),
+			"Start":   fmt.Sprint(n.Start),
+			"IsTight": fmt.Sprint(n.IsTight),
+		}
+	case *ast.ListItem:
+		return map[string]string{"Offset": fmt.Sprint(n.Offset)}
+	case *ast.Emphasis:
+		return map[string]string{"Level": fmt.Sprint(n.Level)}
+	case *ast.Link:
+		return map[string]string{"Destination": string(n.Destination), "Title": string(n.Title)}
+	case *ast.Image:
+		return map[string]string{"Destination": string(n.Destination), "Title": string(n.Title)}
+	case *ast.AutoLink:
+		return map[string]string{"Value": string(n.Label(source))}
+	case *ast.FencedCodeBlock:
+		if n.Info != nil {
+			return map[string]string{"Info": string(n.Info.Segment.Value(source))}
+		}
+	case *ast.String:
+		return map[string]string{"Value": string(n.Value)}
+	case *east.Table:
+		return map[string]string{"Alignments": fmt.Sprint(n.Alignments)}
+	case *east.TaskCheckBox:
+		return map[string]string{"Checked": fmt.Sprint(n.IsChecked)}
+	case *east.Footnote:
+		return map[string]string{"Ref": string(n.Ref), "Index": fmt.Sprint(n.Index)}
+	case *east.FootnoteLink:
+		return map[string]string{"Index": fmt.Sprint(n.Index)}
+	case *FrontMatter:
+		return map[string]string{"Format": fmt.Sprint(n.Format)}
+	}
+	return nil
+}
+
+// dumpNode writes the head of n as ast.DumpHelper does, its attributes go with its fields
+func dumpNode(w io.Writer, n ast.Node, source []byte, level int) {
+	indent := strings.Repeat("    ", level)
+	if t, ok := n.(*ast.Text); ok {
+		fmt.Fprintf(w, "%sText: \"%s\"\n", indent, strings.TrimRight(string(t.Segment.Value(source)), "\n"))
+		return
+	}
+	fmt.Fprintf(w, "%s%s {\n", indent, n.Kind().String())
+
+	indent2 := strings.Repeat("    ", level+1)
+	if n.Type() == ast.TypeBlock {
+		fmt.Fprintf(w, "%sRawText: \"", indent2)
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			fmt.Fprintf(w, "%s", line.Value(source))
+		}
+		fmt.Fprintf(w, "\"\n")
+		fmt.Fprintf(w, "%sHasBlankPreviousLines: %v\n", indent2, n.HasBlankPreviousLines())
+	}
+
+	fields := dumpFields(n, source)
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	for _, attr := range n.Attributes() {
+		if v, ok := attr.Value.([]byte); ok {
+			fields[string(attr.Name)] = string(v)
+		} else {
+			fields[string(attr.Name)] = fmt.Sprint(attr.Value)
+		}
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s: %s\n", indent2, name, fields[name])
+	}
+}
@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// RenderError is an error of rendering a node, Render and Convert return it
+// for errors of node renderers.
+type RenderError struct {
+	// Kind is the kind of the node which failed
+	Kind ast.NodeKind
+
+	// Line and Column are 1-based position of the node in the source, 0 if unknown
+	Line   int
+	Column int
+
+	Err error
+}
+
+func (e *RenderError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%d:%d: %s: %v", e.Line, e.Column, e.Kind, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+func newRenderError(source []byte, n ast.Node, err error) *RenderError {
+	e := &RenderError{
+		Kind: n.Kind(),
+		Err:  err,
+	}
+	if offset := nodeStart(source, n); offset >= 0 && offset <= len(source) {
+		e.Line, e.Column = position(source, offset)
+	}
+	return e
+}
+
+// nodeOffset returns the offset of the node text in the source, or -1; container nodes like
+// lists have no segments of their own, the first one of descendants is taken
+func nodeOffset(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Start
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset := nodeOffset(c); offset >= 0 {
+			return offset
+		}
+	}
+	return -1
+}
+
+// nodeStart returns the offset of the node in the source with its opening delimiters, like "`"
+// of a code span, or -1
+func nodeStart(source []byte, n ast.Node) int {
+	if start, _ := rawRange(source, n); start >= 0 {
+		return start
+	}
+	return nodeOffset(n)
+}
+
+// position returns 1-based line and column (in runes) of the offset
+func position(source []byte, offset int) (int, int) {
+	before := source[:offset]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
+
+// ErrNoMD2MDParser is what Render of MD2MD returns if goldmark hasn't been given
+// goldmark.WithParser(NewParser()).
+var ErrNoMD2MDParser = errors.New("md2md: the parser must be markdown.NewParser(), see goldmark.WithParser()")
+
+// UnsupportedNodes is what md2md does with nodes of kinds without a NodeRendererFunc,
+// e.g. of third-party extensions.
+type UnsupportedNodes int
+
+const (
+	// UnsupportedNodesIgnore copies their source, or renders their children if it is unknown
+	UnsupportedNodesIgnore UnsupportedNodes = iota
+
+	// UnsupportedNodesWarn gives Config.Warn a RenderError for every such node and renders it
+	// as UnsupportedNodesIgnore
+	UnsupportedNodesWarn
+
+	// UnsupportedNodesStrict fails the render with UnsupportedNodesError
+	UnsupportedNodesStrict
+)
+
+// ErrUnsupportedNode is the error of RenderError for a node without a NodeRendererFunc.
+var ErrUnsupportedNode = errors.New("unsupported node kind")
+
+// UnsupportedNodesError lists all nodes without a NodeRendererFunc met in a strict render.
+type UnsupportedNodesError struct {
+	Nodes []*RenderError
+}
+
+func (e *UnsupportedNodesError) Error() string {
+	var nodes []string
+	for _, n := range e.Nodes {
+		if n.Line == 0 {
+			nodes = append(nodes, n.Kind.String())
+		} else {
+			nodes = append(nodes, fmt.Sprintf("%s at line %d", n.Kind, n.Line))
+		}
+	}
+	return "unsupported node kinds: " + strings.Join(nodes, ", ")
+}
+
+func (e *UnsupportedNodesError) Unwrap() []error {
+	var errs []error
+	for _, n := range e.Nodes {
+		errs = append(errs, n)
+	}
+	return errs
+}
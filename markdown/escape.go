@@ -0,0 +1,207 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Text from the source is written as is, it has been markdown already. Text injected into the AST
+// (ast.String nodes, e.g. a translation) is plain text, so md2md escapes the characters which
+// would turn into markup at the place the text lands.
+
+// textPlacement is where an injected text lands in the output
+type textPlacement struct {
+	// the text starts a line, so block markers like "# " or "1. " matter
+	lineStart bool
+
+	// the text ends a heading, so closing "#"s and {attributes} matter
+	headingEnd bool
+}
+
+func placementOf(n ast.Node) textPlacement {
+	return textPlacement{
+		lineStart:  atLineStart(n),
+		headingEnd: n.NextSibling() == nil && n.Parent() != nil && n.Parent().Kind() == ast.KindHeading,
+	}
+}
+
+func atLineStart(n ast.Node) bool {
+	if prev := n.PreviousSibling(); prev != nil {
+		switch prev := prev.(type) {
+		case *ast.Text:
+			return prev.SoftLineBreak() || prev.HardLineBreak()
+		case *ast.String:
+			return bytes.HasSuffix(prev.Value, []byte{'\n'})
+		}
+		return false
+	}
+
+	// inline parents are written with their markers before the text
+	parent := n.Parent()
+	return parent == nil || (parent.Type() == ast.TypeBlock && parent.Kind() != east.KindTableCell)
+}
+
+// inCode tells if the text is a code span content, nothing is escaped there
+func inCode(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindCodeSpan {
+			return true
+		}
+	}
+	return false
+}
+
+var entityRegexp = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{0,31});`)
+
+// escapeText escapes the text for the placement
+func escapeText(s []byte, placement textPlacement) []byte {
+	var out []byte
+	lines := bytes.SplitAfter(s, []byte{'\n'})
+	for i, line := range lines {
+		if i == len(lines)-1 && len(line) == 0 {
+			break
+		}
+
+		eol := bytes.HasSuffix(line, []byte{'\n'})
+		if eol {
+			// trailing spaces would make a hard line break
+			line = append(bytes.TrimRight(line[:len(line)-1], " \t"), '\n')
+		}
+
+		lineStart := i > 0 || placement.lineStart
+		out = append(out, escapeLine(line, lineStart, placement.headingEnd && i == len(lines)-1)...)
+	}
+	return out
+}
+
+func escapeLine(line []byte, lineStart bool, headingEnd bool) []byte {
+	escapeAt := map[int]bool{}
+	if lineStart {
+		// 4+ spaces or a tab would make an indented code block, paragraph text ignores them
+		line = bytes.TrimLeft(line, " \t")
+		if i := blockMarkerAt(line); i >= 0 {
+			escapeAt[i] = true
+		}
+	}
+	if headingEnd {
+		if i := headingSuffixAt(line); i >= 0 {
+			escapeAt[i] = true
+		}
+	}
+
+	isAlnum := func(i int) bool {
+		return i >= 0 && i < len(line) && util.IsAlphaNumeric(line[i])
+	}
+
+	out := make([]byte, 0, len(line))
+	for i, c := range line {
+		escape := false
+		switch c {
+		case '*', '`', '[', ']', '~':
+			escape = true
+		case '_':
+			// intraword _ can't open or close an emphasis
+			escape = !isAlnum(i-1) || !isAlnum(i+1)
+		case '\\':
+			// a backslash at the end may get followed by punctuation of the next node
+			escape = i == len(line)-1 || util.IsPunct(line[i+1]) || line[i+1] == '\n'
+		case '<':
+			// html tags, comments, declarations and autolinks
+			escape = i < len(line)-1 && (util.IsAlphaNumeric(line[i+1]) || bytes.IndexByte([]byte("/!?"), line[i+1]) >= 0)
+		case '&':
+			escape = entityRegexp.Match(line[i:])
+		}
+
+		if escape || escapeAt[i] {
+			out = append(out, '\\')
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// blockMarkerAt returns the position of the char starting a block marker at the line start, or -1
+func blockMarkerAt(line []byte) int {
+	i := 0
+	for i < len(line) && isSpaceOrTab(line[i]) {
+		i++
+	}
+	if i == len(line) {
+		return -1
+	}
+
+	followedBySpace := func(j int) bool {
+		return j >= len(line) || util.IsSpace(line[j])
+	}
+
+	switch c := line[i]; {
+	case c == '#':
+		j := i
+		for j < len(line) && line[j] == '#' {
+			j++
+		}
+		if j-i <= 6 && followedBySpace(j) {
+			return i
+		}
+	case c == '>':
+		return i
+	case c == '-' || c == '+' || c == ':':
+		// bullet list item, definition description
+		if followedBySpace(i + 1) {
+			return i
+		}
+		if c == '-' && isLineOf(line[i:], '-') {
+			// setext underline or thematic break
+			return i
+		}
+	case c == '=':
+		if isLineOf(line[i:], '=') {
+			return i
+		}
+	case c >= '0' && c <= '9':
+		j := i
+		for j < len(line) && line[j] >= '0' && line[j] <= '9' {
+			j++
+		}
+		if j-i <= 9 && j < len(line) && (line[j] == '.' || line[j] == ')') && followedBySpace(j+1) {
+			return j
+		}
+	}
+	return -1
+}
+
+func isLineOf(line []byte, c byte) bool {
+	for _, b := range line {
+		if b != c && !util.IsSpace(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// headingSuffixAt returns the position of the char starting closing "#"s or {attributes}
+// at the end of heading content, or -1
+func headingSuffixAt(line []byte) int {
+	line = bytes.TrimRight(line, " \t\n")
+	if len(line) == 0 {
+		return -1
+	}
+
+	switch line[len(line)-1] {
+	case '#':
+		i := len(line) - 1
+		for i > 0 && line[i-1] == '#' {
+			i--
+		}
+		if i == 0 || isSpaceOrTab(line[i-1]) {
+			return i
+		}
+	case '}':
+		return bytes.LastIndexByte(line, '{')
+	}
+	return -1
+}
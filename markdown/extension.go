@@ -0,0 +1,115 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldrender "github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// MD2MD is a goldmark extension which renders markdown back to markdown. It installs the Renderer
+// with its Context; the parser must be NewParser(), it can't take the place of the one given since
+// parser options and parsers of extensions would be lost, so Render fails with ErrNoMD2MDParser
+// otherwise. Extensions contribute their markdown node renderers the same way they do html ones,
+// so it must go before them:
+//
+//	goldmark.New(
+//		goldmark.WithParser(markdown.NewParser()),
+//		goldmark.WithExtensions(markdown.NewMD2MD(), markdown.Table, markdown.Footnote),
+//	)
+type MD2MD struct {
+	// Options configure rendering
+	Options []Option
+
+	// NodeRenderers are extra markdown NodeRenderers, see MarkdownNodeRendererPriority
+	NodeRenderers []util.PrioritizedValue
+
+	// VerbosePadding numbers every padding, for debugging
+	VerbosePadding bool
+}
+
+func NewMD2MD(opts ...Option) *MD2MD {
+	return &MD2MD{
+		Options: opts,
+	}
+}
+
+func (e *MD2MD) Extend(m goldmark.Markdown) {
+	context := NewContext(e.VerbosePadding)
+	nodeRenderers := append([]util.PrioritizedValue{
+		util.Prioritized(NewNodeRenderer(context, e.Options...), MarkdownNodeRendererPriority),
+	}, e.NodeRenderers...)
+	r := NewRenderer(context, nodeRenderers...)
+	if _, ok := m.Parser().(*md2mdParser); !ok {
+		r.(*Renderer).err = ErrNoMD2MDParser
+	}
+	m.SetRenderer(r)
+}
+
+// extensionNodeRendererPriority is what goldmark extensions go with
+const extensionNodeRendererPriority = 500
+
+// addNodeRenderer adds the markdown node renderer of an extension if the renderer is the md2md one
+func addNodeRenderer(m goldmark.Markdown, newNodeRenderer func(*Context) NodeRenderer) bool {
+	r, ok := m.Renderer().(*Renderer)
+	if ok {
+		m.Renderer().AddOptions(goldrender.WithNodeRenderers(
+			util.Prioritized(newNodeRenderer(r.Context()), extensionNodeRendererPriority),
+		))
+	}
+	return ok
+}
+
+// markdownExtension is a goldmark extension with its markdown node renderer
+type markdownExtension struct {
+	goldmark.Extender
+	newNodeRenderer func(*Context) NodeRenderer
+}
+
+func (e *markdownExtension) Extend(m goldmark.Markdown) {
+	e.Extender.Extend(m)
+	addNodeRenderer(m, e.newNodeRenderer)
+}
+
+// Table is extension.Table which renders to markdown too.
+var Table goldmark.Extender = &markdownExtension{
+	Extender:        extension.Table,
+	newNodeRenderer: newTableNodeRenderer,
+}
+
+// DefinitionList is extension.DefinitionList which renders to markdown too.
+var DefinitionList goldmark.Extender = &markdownExtension{
+	Extender:        extension.DefinitionList,
+	newNodeRenderer: newDefinitionListNodeRenderer,
+}
+
+// Strikethrough is extension.Strikethrough which renders to markdown too.
+var Strikethrough goldmark.Extender = &markdownExtension{
+	Extender:        extension.Strikethrough,
+	newNodeRenderer: newStrikethroughNodeRenderer,
+}
+
+// TaskList is extension.TaskList which renders to markdown too.
+var TaskList goldmark.Extender = &markdownExtension{
+	Extender:        extension.TaskList,
+	newNodeRenderer: newTaskListNodeRenderer,
+}
+
+type footnoteExtension struct {
+}
+
+// Footnote is extension.Footnote which renders to markdown too.
+var Footnote goldmark.Extender = &footnoteExtension{}
+
+func (e *footnoteExtension) Extend(m goldmark.Markdown) {
+	extension.Footnote.Extend(m)
+	if addNodeRenderer(m, newFootnoteNodeRenderer) {
+		m.Parser().AddOptions(
+			parser.WithASTTransformers(
+				util.Prioritized(mdTransformFunc(keepFootnotes), keepFootnotesPriority),
+				util.Prioritized(mdTransformFunc(placeFootnotes), placeFootnotesPriority),
+			),
+		)
+	}
+}
@@ -0,0 +1,104 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestMD2MDExtension(t *testing.T) {
+	src := "| a   | b   |\n| --- | --- |\n| c   | d   |\n\nText[^1] ~~struck~~\n\n[^1]: Note"
+
+	md := goldmark.New(
+		goldmark.WithParser(NewParser()),
+		goldmark.WithExtensions(
+			NewMD2MD(WithTablePadding(true)),
+			Table,
+			Strikethrough,
+			Footnote,
+		),
+	)
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Convert([]byte(src), &buf))
+	assert.Equal(t, src, buf.String())
+
+	// the same extensions render html without MD2MD
+	md = goldmark.New(goldmark.WithExtensions(Table, Strikethrough))
+	buf.Reset()
+	assert.NoError(t, md.Convert([]byte("~~struck~~"), &buf))
+	assert.Equal(t, "<p><del>struck</del></p>\n", buf.String())
+}
+
+func TestMD2MDExtensionParser(t *testing.T) {
+	// options of the parser are kept
+	md := goldmark.New(
+		goldmark.WithParser(NewParser()),
+		goldmark.WithParserOptions(parser.WithHeadingAttribute()),
+		goldmark.WithExtensions(NewMD2MD()),
+	)
+	var buf bytes.Buffer
+	assert.NoError(t, md.Convert([]byte("# Heading {#id}"), &buf))
+	assert.Equal(t, "# Heading {#id}", buf.String())
+
+	md = goldmark.New(goldmark.WithExtensions(NewMD2MD()))
+	buf.Reset()
+	assert.ErrorIs(t, md.Convert([]byte("text"), &buf), ErrNoMD2MDParser)
+	assert.Empty(t, buf.String())
+}
+
+func TestMD2MDAttributes(t *testing.T) {
+	src := []byte("***\n\n2. [a][b] _c_ <http://d>\n\n~~~\ne\n~~~\n\n[b]: /b")
+	doc := NewParser().Parse(text.NewReader(src))
+
+	// md2md details of nodes aren't attributes, which renderers write out
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		assert.Nil(t, n.Attributes(), n.Kind().String())
+		return ast.WalkContinue, nil
+	})
+
+	var buf bytes.Buffer
+	md := newMarkdown(NewOptions())
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+	assert.Equal(t, string(src), buf.String())
+}
+
+type upperTaskCheckBoxRenderer struct {
+}
+
+func (r *upperTaskCheckBoxRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(east.KindTaskCheckBox, func(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.(*east.TaskCheckBox).IsChecked {
+			_, _ = w.WriteString("[X] ")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type upperTaskList struct {
+}
+
+// Extend shows how an extension outside of the package adds its markdown node renderer
+func (e *upperTaskList) Extend(m goldmark.Markdown) {
+	extension.TaskList.Extend(m)
+	if _, ok := m.Renderer().(*Renderer); ok {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&upperTaskCheckBoxRenderer{}, 500)))
+	}
+}
+
+func TestMD2MDExtensionNodeRenderer(t *testing.T) {
+	md := goldmark.New(goldmark.WithParser(NewParser()), goldmark.WithExtensions(NewMD2MD(), &upperTaskList{}))
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Convert([]byte("- [x] done"), &buf))
+	assert.Equal(t, "- [X] done", buf.String())
+}
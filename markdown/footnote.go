@@ -0,0 +1,175 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+type footnoteNodeRenderer struct {
+	*nodeRenderer
+}
+
+func newFootnoteNodeRenderer(context *Context) NodeRenderer {
+	return &footnoteNodeRenderer{newNodeRenderer(context)}
+}
+
+func (r *footnoteNodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(east.KindFootnoteList, r.renderFootnoteList)
+	reg.Register(east.KindFootnote, r.renderFootnote)
+	reg.Register(east.KindFootnoteLink, r.renderFootnoteLink)
+	reg.Register(east.KindFootnoteBacklink, r.renderFootnoteBacklink)
+}
+
+// footnote definitions are collected by goldmark into a FootnoteList at the end of the document,
+// sorted by the first reference; md2md puts them back to their places in the source, see
+// placeFootnotes(), and writes them as:
+//
+//	[^label]: first para
+//
+//	    second para
+const footnoteIndent = "    "
+
+// keepFootnotes keeps footnote definitions nobody refers to, extension.Footnote drops them
+// otherwise; it must run before extension.NewFootnoteASTTransformer()
+func keepFootnotes(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		list, ok := n.(*east.FootnoteList)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+			if fn := c.(*east.Footnote); fn.Index < 0 {
+				list.Count++
+				fn.Index = list.Count
+			}
+		}
+		return ast.WalkStop, nil
+	})
+}
+
+// keepFootnotesPriority goes before extension.NewFootnoteASTTransformer() one (999), the lower the earlier
+const keepFootnotesPriority = 998
+
+// placeFootnotes moves footnote definitions out of the FootnoteList to their places in the
+// source, so a definition in the middle of the document stays there; definitions without
+// content have no place and stay in the list. It must run after extension.NewFootnoteASTTransformer()
+func placeFootnotes(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	list, ok := doc.LastChild().(*east.FootnoteList)
+	if !ok {
+		return
+	}
+
+	for c := list.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		if offset := nodeOffset(c); offset >= 0 {
+			list.RemoveChild(list, c)
+			parent, before := footnotePlace(doc, offset)
+			if before == nil {
+				parent.AppendChild(parent, c)
+			} else {
+				parent.InsertBefore(parent, before, c)
+			}
+		}
+		c = next
+	}
+	if !list.HasChildren() {
+		doc.RemoveChild(doc, list)
+	}
+}
+
+// placeFootnotesPriority goes after extension.NewFootnoteASTTransformer() one (999)
+const placeFootnotesPriority = 1000
+
+// footnotePlace returns the container and the block the footnote definition at the offset
+// goes before, nil for the end of the container
+func footnotePlace(parent ast.Node, offset int) (ast.Node, ast.Node) {
+	for c := parent.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Kind() == east.KindFootnoteList {
+			return parent, c
+		}
+		start := nodeOffset(c)
+		if start < 0 {
+			continue
+		}
+		if start > offset {
+			if prev := c.PreviousSibling(); prev != nil && parent.Kind() == ast.KindList {
+				// lists have nothing but items, so it is the end of the previous one
+				return prev, nil
+			}
+			return parent, c
+		}
+		if offset < nodeStop(c) && c.FirstChild() != nil && c.FirstChild().Type() == ast.TypeBlock {
+			return footnotePlace(c, offset)
+		}
+	}
+	return parent, nil
+}
+
+func footnoteRef(n *east.FootnoteLink) []byte {
+	var root ast.Node = n
+	for root.Parent() != nil {
+		root = root.Parent()
+	}
+
+	var ref []byte
+	_ = ast.Walk(root, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if fn, ok := c.(*east.Footnote); ok && entering {
+			if fn.Index == n.Index {
+				ref = fn.Ref
+				return ast.WalkStop, nil
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return ref
+}
+
+func (r *nodeRenderer) renderFootnoteList(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderFootnote(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*east.Footnote)
+	if entering {
+		_, _ = w.WriteString("[^")
+		_, _ = w.Write(n.Ref)
+		_, _ = w.WriteString("]:")
+		if c := n.FirstChild(); c != nil && c.Type() == ast.TypeBlock {
+			// an empty one has backlinks only
+			_ = w.WriteByte(' ')
+		}
+
+		r.context.PushStack(w, footnoteIndent)
+	} else {
+		r.context.PopStack(w)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderFootnoteLink(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*east.FootnoteLink)
+	_, _ = w.WriteString("[^")
+	_, _ = w.Write(footnoteRef(n))
+	_ = w.WriteByte(']')
+	return ast.WalkContinue, nil
+}
+
+func (r *nodeRenderer) renderFootnoteBacklink(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	// there is no markdown syntax for backlinks, they are generated by html renderer
+	return ast.WalkContinue, nil
+}
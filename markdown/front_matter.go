@@ -0,0 +1,274 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatterFormat is the language of front matter.
+type FrontMatterFormat int
+
+const (
+	// FrontMatterYAML is delimited with "---" lines, Jekyll and Hugo style
+	FrontMatterYAML FrontMatterFormat = iota + 1
+
+	// FrontMatterTOML is delimited with "+++" lines, Hugo style
+	FrontMatterTOML
+)
+
+// FrontMatter is a block of metadata at the very beginning of a document like
+//
+//	---
+//	title: Hello
+//	---
+//
+// md2md writes it back as is, md2html leaves it out.
+type FrontMatter struct {
+	ast.BaseBlock
+
+	Format FrontMatterFormat
+}
+
+// IsRaw implements Node.IsRaw, front matter isn't markdown.
+func (n *FrontMatter) IsRaw() bool {
+	return true
+}
+
+// Dump implements Node.Dump.
+func (n *FrontMatter) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Format": fmt.Sprint(n.Format),
+	}, nil)
+}
+
+// KindFrontMatter is a NodeKind of the FrontMatter node.
+var KindFrontMatter = ast.NewNodeKind("FrontMatter")
+
+// Kind implements Node.Kind.
+func (n *FrontMatter) Kind() ast.NodeKind {
+	return KindFrontMatter
+}
+
+func NewFrontMatter(format FrontMatterFormat) *FrontMatter {
+	return &FrontMatter{
+		Format: format,
+	}
+}
+
+// Fields decodes the front matter, the lines between delimiters.
+func (n *FrontMatter) Fields(source []byte) (map[string]any, error) {
+	var content []byte
+	for i := 1; i < n.Lines().Len()-1; i++ {
+		line := n.Lines().At(i)
+		content = append(content, line.Value(source)...)
+	}
+	return decodeFrontMatter(n.Format, content)
+}
+
+// GetFrontMatter returns front matter of the document or nil.
+func GetFrontMatter(doc ast.Node) *FrontMatter {
+	if n, ok := doc.FirstChild().(*FrontMatter); ok {
+		return n
+	}
+	return nil
+}
+
+// ParseFrontMatter decodes front matter at the beginning of source, fields are nil
+// if there is no front matter. Callers decide which fields are translatable, like title and description.
+func ParseFrontMatter(source []byte) (map[string]any, error) {
+	format, lines := frontMatterLines(source)
+	if format == 0 {
+		return nil, nil
+	}
+	content := source[lines[1].Start:lines[len(lines)-1].Start]
+	return decodeFrontMatter(format, content)
+}
+
+func decodeFrontMatter(format FrontMatterFormat, content []byte) (map[string]any, error) {
+	fields := map[string]any{}
+	var err error
+	switch format {
+	case FrontMatterYAML:
+		err = yaml.Unmarshal(content, &fields)
+	case FrontMatterTOML:
+		err = toml.Unmarshal(content, &fields)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("front matter: %w", err)
+	}
+	return fields, nil
+}
+
+func frontMatterFormat(line []byte) FrontMatterFormat {
+	switch string(util.TrimRightSpace(line)) {
+	case "---":
+		return FrontMatterYAML
+	case "+++":
+		return FrontMatterTOML
+	}
+	return 0
+}
+
+func isFrontMatterEnd(format FrontMatterFormat, line []byte) bool {
+	line = util.TrimRightSpace(line)
+	if format == FrontMatterYAML {
+		return string(line) == "---" || string(line) == "..."
+	}
+	return string(line) == "+++"
+}
+
+// frontMatterLines finds front matter at the beginning of source and returns its lines
+// including both delimiters; front matter which isn't closed isn't one
+func frontMatterLines(source []byte) (FrontMatterFormat, []text.Segment) {
+	var lines []text.Segment
+	var format FrontMatterFormat
+	for start := 0; start < len(source); {
+		stop := len(source)
+		if i := bytes.IndexByte(source[start:], '\n'); i >= 0 {
+			stop = start + i + 1
+		}
+		line := source[start:stop]
+		lines = append(lines, text.NewSegment(start, stop))
+		start = stop
+
+		if len(lines) == 1 {
+			if format = frontMatterFormat(line); format == 0 {
+				return 0, nil
+			}
+		} else if isFrontMatterEnd(format, line) {
+			return format, lines
+		}
+	}
+	return 0, nil
+}
+
+type frontMatterParser struct {
+}
+
+var defaultFrontMatterParser = &frontMatterParser{}
+
+func (b *frontMatterParser) Trigger() []byte {
+	return []byte{'-', '+'}
+}
+
+func (b *frontMatterParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	_, segment := reader.PeekLine()
+	if segment.Start != 0 || parent.Kind() != ast.KindDocument {
+		return nil, parser.NoChildren
+	}
+
+	format, lines := frontMatterLines(reader.Source())
+	if format == 0 {
+		return nil, parser.NoChildren
+	}
+
+	node := NewFrontMatter(format)
+	node.Lines().AppendAll(lines)
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (b *frontMatterParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	// the lines are known since Open, they are skipped up to the closing delimiter
+	line, segment := reader.PeekLine()
+	newline := 0
+	if line[len(line)-1] == '\n' {
+		newline = 1
+	}
+	reader.Advance(segment.Len() - newline)
+
+	last := node.Lines().At(node.Lines().Len() - 1)
+	if segment.Start >= last.Start {
+		return parser.Close
+	}
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *frontMatterParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+}
+
+func (b *frontMatterParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (b *frontMatterParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// frontMatterParserPriority is less than thematic break and setext heading ones
+const frontMatterParserPriority = 50
+
+type frontMatterExtension struct {
+}
+
+// FrontMatterExtension is a goldmark extension which parses front matter, md2md writes it back as is,
+// other renderers leave it out.
+var FrontMatterExtension = &frontMatterExtension{}
+
+func (e *frontMatterExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(
+		util.Prioritized(defaultFrontMatterParser, frontMatterParserPriority),
+	))
+	if !addNodeRenderer(m, newFrontMatterNodeRenderer) {
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(
+			util.Prioritized(&frontMatterHTMLRenderer{}, extensionNodeRendererPriority),
+		))
+	}
+}
+
+// frontMatterHTMLRenderer leaves front matter out of html
+type frontMatterHTMLRenderer struct {
+}
+
+func (r *frontMatterHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindFrontMatter, func(util.BufWriter, []byte, ast.Node, bool) (ast.WalkStatus, error) {
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+type frontMatterNodeRenderer struct {
+	*nodeRenderer
+}
+
+func newFrontMatterNodeRenderer(context *Context) NodeRenderer {
+	return &frontMatterNodeRenderer{newNodeRenderer(context)}
+}
+
+func (r *frontMatterNodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(KindFrontMatter, r.renderFrontMatter)
+}
+
+func (r *nodeRenderer) renderFrontMatter(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		b := line.Value(source)
+		if i == l-1 {
+			b = bytes.TrimRight(b, "\n")
+		}
+		_, _ = w.Write(b)
+	}
+	return ast.WalkContinue, nil
+}
+
+// frontMatterSeparator keeps the source blank line, or its absence, after front matter
+func frontMatterSeparator(n ast.Node) string {
+	if n.NextSibling().HasBlankPreviousLines() {
+		return "\n\n"
+	}
+	return "\n"
+}
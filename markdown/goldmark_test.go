@@ -433,13 +433,13 @@ para after 3
 		assert.NoError(t, err)
 	}
 
-	md2md := true           // false //
-	verbosePadding := false // true //
+	format := FormatMarkdown // FormatHTML //
+	verbosePadding := false  // true //
 
 	var writer io.Writer = os.Stderr // os.Stdout //
 	writer = ZeroBufWriter{writer}
 
-	err := Convert(data, writer, md2md, true, verbosePadding)
+	err := Convert(data, writer, WithFormat(format), WithDump(os.Stdout), WithVerbosePadding(verbosePadding))
 	assert.NoError(t, err)
 }
 
@@ -0,0 +1,167 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// defaultEmphasisMarker is used when the source one is unknown, e.g. for nodes added to the AST
+const defaultEmphasisMarker = '*'
+
+// * or _ the emphasis has been written with
+const emphasisMarkerAttr = "md2md-emphasis-marker"
+
+// emphasisMarkerParser wraps parser.NewEmphasisParser() to save the delimiter char in emphasis nodes;
+// the opener char is the one delimiters are matched by, ast.Emphasis has no segments to look at
+type emphasisMarkerParser struct {
+	parser.InlineParser
+}
+
+func newEmphasisMarkerParser() parser.InlineParser {
+	return &emphasisMarkerParser{
+		InlineParser: parser.NewEmphasisParser(),
+	}
+}
+
+func (p *emphasisMarkerParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	node := p.InlineParser.Parse(parent, block, pc)
+	if d, ok := node.(*parser.Delimiter); ok {
+		d.Processor = &emphasisMarkerProcessor{
+			DelimiterProcessor: d.Processor,
+			marker:             d.Char,
+			parent:             parent,
+		}
+	}
+	return node
+}
+
+// emphasisMarkerProcessor is called with the opener delimiter, see parser.ProcessDelimiters()
+type emphasisMarkerProcessor struct {
+	parser.DelimiterProcessor
+	marker byte
+	parent ast.Node
+}
+
+func (p *emphasisMarkerProcessor) OnMatch(consumes int) ast.Node {
+	node := p.DelimiterProcessor.OnMatch(consumes)
+	setAttribute(p.parent, node, emphasisMarkerAttr, p.marker)
+	return node
+}
+
+func emphasisMarker(n ast.Node) byte {
+	if v, ok := attribute(n, emphasisMarkerAttr); ok {
+		return v.(byte)
+	}
+	return defaultEmphasisMarker
+}
+
+// codeSpanFence returns the shortest backtick run which doesn't occur in the content,
+// but not shorter than the source one
+func codeSpanFence(content string, sourceLength int) string {
+	runs := map[int]bool{}
+	for i := 0; i < len(content); {
+		if content[i] != '`' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(content) && content[j] == '`' {
+			j++
+		}
+		runs[j-i] = true
+		i = j
+	}
+
+	length := max(sourceLength, 1)
+	for runs[length] {
+		length++
+	}
+	return strings.Repeat("`", length)
+}
+
+// codeSpanMarkdown returns the code span n with the content, fenced as in the source if possible
+func codeSpanMarkdown(source []byte, n ast.Node, content string) string {
+	sourceLength, padded := codeSpanSource(source, n)
+	fence := codeSpanFence(content, sourceLength)
+	if padded || codeSpanNeedsPadding(content) {
+		content = " " + content + " "
+	}
+	return fence + content + fence
+}
+
+// codeSpanNeedsPadding tells if the content must be separated from the fence with spaces:
+// a backtick next to the fence would extend it, and a single space is stripped from both sides
+// of the content which begins and ends with spaces
+func codeSpanNeedsPadding(content string) bool {
+	if content == "" {
+		return false
+	}
+	first, last := content[0], content[len(content)-1]
+	if first == '`' || last == '`' {
+		return true
+	}
+	return util.IsSpace(first) && util.IsSpace(last) && strings.TrimSpace(content) != ""
+}
+
+// codeSpanSource returns the fence length and whether the content has been padded with spaces
+// in the source; zero length means the source is unknown
+func codeSpanSource(source []byte, n ast.Node) (int, bool) {
+	first, ok := n.FirstChild().(*ast.Text)
+	if !ok {
+		return 0, false
+	}
+	last, ok := n.LastChild().(*ast.Text)
+	if !ok {
+		return 0, false
+	}
+
+	start := first.Segment.Start
+	padded := start > 0 && source[start-1] == ' ' &&
+		last.Segment.Stop < len(source) && source[last.Segment.Stop] == ' '
+	if padded {
+		start--
+	}
+
+	length := 0
+	for i := start - 1; i >= 0 && source[i] == '`'; i-- {
+		length++
+	}
+	return length, padded && length > 0
+}
+
+// autolink is written in angle brackets like <https://example.com>, otherwise it's a bare URL
+// found by linkify
+const angleAutoLinkAttr = "md2md-angle-autolink"
+
+// autoLinkFormParser wraps parser.NewAutoLinkParser() to tell <...> autolinks from linkify ones,
+// both are ast.AutoLink
+type autoLinkFormParser struct {
+	parser.InlineParser
+}
+
+func newAutoLinkFormParser() parser.InlineParser {
+	return &autoLinkFormParser{
+		InlineParser: parser.NewAutoLinkParser(),
+	}
+}
+
+func (p *autoLinkFormParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	node := p.InlineParser.Parse(parent, block, pc)
+	if node != nil {
+		setAttribute(parent, node, angleAutoLinkAttr, true)
+	}
+	return node
+}
+
+// isAngleAutoLink tells if the autolink is to be written in angle brackets; emails are always
+// because linkify doesn't look for them
+func isAngleAutoLink(n *ast.AutoLink) bool {
+	if _, ok := attribute(n, angleAutoLinkAttr); ok {
+		return true
+	}
+	return n.AutoLinkType == ast.AutoLinkEmail
+}
@@ -0,0 +1,160 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// linkSyntax is how the destination and the title of an inline link are written in the source,
+// ast.Link and ast.Image keep their values only
+type linkSyntax struct {
+	// <...> destination
+	angleDestination bool
+
+	// ", ' or ( the title is opened with
+	titleOpener byte
+}
+
+const inlineLinkAttr = "md2md-inline-link"
+
+// inlineLinkSyntax finds out the syntax from (...) of an inline link, given without parentheses
+func inlineLinkSyntax(b []byte) *linkSyntax {
+	b = util.TrimLeftSpace(b)
+	syntax := &linkSyntax{
+		angleDestination: len(b) > 0 && b[0] == '<',
+	}
+
+	// the title is the last one, so is its closer
+	if b = util.TrimRightSpace(b); len(b) > 0 {
+		switch b[len(b)-1] {
+		case '"', '\'':
+			syntax.titleOpener = b[len(b)-1]
+		case ')':
+			syntax.titleOpener = '('
+		}
+	}
+	return syntax
+}
+
+// isEscaped tells if the char at i is backslash escaped
+func isEscaped(b []byte, i int) bool {
+	backslashes := 0
+	for j := i - 1; j >= 0 && b[j] == '\\'; j-- {
+		backslashes++
+	}
+	return backslashes%2 == 1
+}
+
+// containsUnescaped tells if b has any of chars which is not backslash escaped
+func containsUnescaped(b []byte, chars string) bool {
+	for i, c := range b {
+		if bytes.IndexByte([]byte(chars), c) >= 0 && !isEscaped(b, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeUnescaped puts a backslash before every char of chars which is not escaped yet
+func escapeUnescaped(b []byte, chars string) []byte {
+	var out []byte
+	for i, c := range b {
+		if bytes.IndexByte([]byte(chars), c) >= 0 && !isEscaped(b, i) {
+			out = append(out, '\\')
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// needsAngleDestination tells if the destination can't be written bare:
+// it's empty, has spaces or control chars, starts with < or has unbalanced parentheses
+func needsAngleDestination(destination []byte) bool {
+	if len(destination) == 0 || destination[0] == '<' {
+		return true
+	}
+
+	opened := 0
+	for i, c := range destination {
+		switch {
+		case c <= ' ' || c == 0x7f:
+			return true
+		case isEscaped(destination, i):
+		case c == '(':
+			opened++
+		case c == ')':
+			opened--
+			if opened < 0 {
+				return true
+			}
+		}
+	}
+	return opened != 0
+}
+
+// titleCloser returns the char to close the title opened with the opener
+func titleCloser(opener byte) byte {
+	if opener == '(' {
+		return ')'
+	}
+	return opener
+}
+
+// canQuoteTitle tells if the title can be written with the opener as is
+func canQuoteTitle(title []byte, opener byte) bool {
+	chars := string(titleCloser(opener))
+	if opener == '(' {
+		chars = "()"
+	}
+	return !containsUnescaped(title, chars)
+}
+
+func writeLinkDestination(w util.BufWriter, destination []byte, angle bool) {
+	if angle || needsAngleDestination(destination) {
+		_ = w.WriteByte('<')
+		_, _ = w.Write(escapeUnescaped(destination, "<>"))
+		_ = w.WriteByte('>')
+		return
+	}
+	_, _ = w.Write(destination)
+}
+
+func writeLinkTitle(w util.BufWriter, title []byte, opener byte) {
+	if opener == 0 || !canQuoteTitle(title, opener) {
+		opener = '"'
+		for _, c := range []byte{'"', '\'', '('} {
+			if canQuoteTitle(title, c) {
+				opener = c
+				break
+			}
+		}
+		if !canQuoteTitle(title, opener) {
+			title = escapeUnescaped(title, `"`)
+		}
+	}
+
+	_ = w.WriteByte(opener)
+	_, _ = w.Write(title)
+	_ = w.WriteByte(titleCloser(opener))
+}
+
+// finishLink writes the inline link destination and title; they are markdown as they come from the parser,
+// so only what breaks the syntax is changed, and the source syntax is kept while it's still valid.
+func finishLink(w util.BufWriter, n ast.Node, destination []byte, title []byte) {
+	syntax := &linkSyntax{}
+	if v, ok := attribute(n, inlineLinkAttr); ok {
+		syntax = v.(*linkSyntax)
+	}
+
+	_, _ = w.WriteString("](")
+	if len(destination) > 0 || title != nil || syntax.angleDestination {
+		writeLinkDestination(w, destination, syntax.angleDestination)
+	}
+	if title != nil {
+		_ = w.WriteByte(' ')
+		writeLinkTitle(w, title, syntax.titleOpener)
+	}
+	_ = w.WriteByte(')')
+}
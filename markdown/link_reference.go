@@ -0,0 +1,336 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// LinkReferenceDefinitions is a block of link reference definitions like
+//
+//	[label]: /url "title"
+//
+// goldmark collects them into parser.Context and drops them from the AST,
+// md2md puts them back where they appeared, as is.
+type LinkReferenceDefinitions struct {
+	ast.BaseBlock
+
+	// Raw replaces the source lines when set, e.g. by a Transformer
+	Raw []byte
+}
+
+// Text returns the definitions as written in the source, or Raw.
+func (n *LinkReferenceDefinitions) Text(source []byte) []byte {
+	if n.Raw != nil {
+		return n.Raw
+	}
+	var b []byte
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		b = append(b, line.Value(source)...)
+	}
+	return b
+}
+
+// IsRaw implements Node.IsRaw, definitions aren't parsed as inlines.
+func (n *LinkReferenceDefinitions) IsRaw() bool {
+	return true
+}
+
+// Dump implements Node.Dump.
+func (n *LinkReferenceDefinitions) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindLinkReferenceDefinitions is a NodeKind of the LinkReferenceDefinitions node.
+var KindLinkReferenceDefinitions = ast.NewNodeKind("LinkReferenceDefinitions")
+
+// Kind implements Node.Kind.
+func (n *LinkReferenceDefinitions) Kind() ast.NodeKind {
+	return KindLinkReferenceDefinitions
+}
+
+func NewLinkReferenceDefinitions() *LinkReferenceDefinitions {
+	return &LinkReferenceDefinitions{}
+}
+
+type linkReferenceDefinitionsTransformer struct {
+}
+
+// Transform wraps parser.LinkReferenceParagraphTransformer: the definitions can only start a paragraph,
+// so the lines it removes are the head of the paragraph.
+func (t *linkReferenceDefinitionsTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	// a copy, the transformer changes lines in place
+	lines := slices.Clone(node.Lines().Sliced(0, node.Lines().Len()))
+	parent, next := node.Parent(), node.NextSibling()
+
+	parser.LinkReferenceParagraphTransformer.Transform(node, reader, pc)
+
+	removed := len(lines) - node.Lines().Len()
+	if removed == 0 {
+		return
+	}
+
+	defs := NewLinkReferenceDefinitions()
+	defs.Lines().AppendAll(lines[:removed])
+	defs.SetBlankPreviousLines(node.HasBlankPreviousLines())
+
+	if node.Parent() == nil {
+		// all lines are definitions, the paragraph is replaced with an empty TextBlock
+		textBlock := parent.LastChild()
+		if next != nil {
+			textBlock = next.PreviousSibling()
+		}
+		parent.ReplaceChild(parent, textBlock, defs)
+	} else {
+		parent.InsertBefore(parent, node, defs)
+	}
+}
+
+// linkDefinitionTitles returns ranges of titles of the definitions in b, without their quotes
+func linkDefinitionTitles(b []byte) [][2]int {
+	var titles [][2]int
+	skip := func(i int, chars string) int {
+		for i < len(b) && bytes.IndexByte([]byte(chars), b[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	// closer returns the offset of the first unescaped c from i, -1 if there is none
+	closer := func(i int, c byte) int {
+		for ; i < len(b); i++ {
+			if b[i] == c && !isEscaped(b, i) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i := skip(0, " \t\n"); i < len(b) && b[i] == '['; i = skip(i, " \t\n") {
+		// [label]:
+		if i = closer(i+1, ']'); i < 0 || i+1 >= len(b) || b[i+1] != ':' {
+			break
+		}
+		i = skip(i+2, " \t")
+		i = skip(skip(i, "\n"), " \t")
+
+		// the destination
+		if i < len(b) && b[i] == '<' {
+			if i = closer(i+1, '>'); i < 0 {
+				break
+			}
+			i++
+		}
+		for i < len(b) && b[i] > ' ' {
+			i++
+		}
+
+		// the title, on the same line or the next one, with nothing after it
+		j := skip(i, " \t")
+		if j < len(b) && b[j] == '\n' {
+			j = skip(j+1, " \t")
+		}
+		if j < len(b) && bytes.IndexByte([]byte(`"'(`), b[j]) >= 0 {
+			if k := closer(j+1, titleCloser(b[j])); k >= 0 && util.IsBlank(b[k+1:lineEnd(b, k+1)]) {
+				titles = append(titles, [2]int{j + 1, k})
+				i = k + 1
+			}
+		}
+		i = lineEnd(b, i)
+	}
+	return titles
+}
+
+// setTitle replaces the title of the i-th definition with a title, see linkDefinitionTitles
+func (n *LinkReferenceDefinitions) setTitle(source []byte, i int, title []byte) error {
+	raw := n.Text(source)
+	titles := linkDefinitionTitles(raw)
+	if i >= len(titles) {
+		return fmt.Errorf("no title %d in link reference definitions", i+1)
+	}
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	writeLinkTitle(w, title, raw[titles[i][0]-1])
+	_ = w.Flush()
+	n.Raw = slices.Concat(raw[:titles[i][0]-1], b.Bytes(), raw[titles[i][1]+1:])
+	return nil
+}
+
+func (r *nodeRenderer) renderLinkReferenceDefinitions(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	if raw := n.(*LinkReferenceDefinitions).Raw; raw != nil {
+		rawWrite(w, bytes.TrimRight(raw, "\n"), r.context)
+		return ast.WalkContinue, nil
+	}
+
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		b := line.Value(source)
+		if i == l-1 {
+			b = bytes.TrimRight(b, "\n")
+		}
+		_, _ = w.Write(b)
+		if i != l-1 {
+			r.context.Pad(w)
+		}
+	}
+	return ast.WalkContinue, nil
+}
+
+// linkForm is how a link or an image is written in the source
+type linkForm int
+
+const (
+	linkInline    linkForm = iota // [text](/url "title")
+	linkFull                      // [text][label]
+	linkCollapsed                 // [label][]
+	linkShortcut                  // [label]
+)
+
+// linkReference is saved in links and images which refer to a link reference definition
+type linkReference struct {
+	form linkForm
+
+	// label for full form, link text for collapsed and shortcut ones
+	label []byte
+
+	// resolved from the definition; the reference form is kept only while
+	// the link still points to the same place
+	destination []byte
+	title       []byte
+}
+
+const linkReferenceAttr = "md2md-link-reference"
+
+var linkOpenersKey = parser.NewContextKey()
+
+// linkFormParser wraps parser.NewLinkParser() to find out the form a link has been written in.
+// Positions of '[' openers are tracked the same way the link parser tracks its label states:
+// every ']' closes the last opener.
+type linkFormParser struct {
+	parser.InlineParser
+}
+
+func newLinkFormParser() parser.InlineParser {
+	return &linkFormParser{
+		InlineParser: parser.NewLinkParser(),
+	}
+}
+
+func (p *linkFormParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+
+	var openers []int
+	if v := pc.Get(linkOpenersKey); v != nil {
+		openers = v.([]int)
+	}
+
+	if line[0] != ']' {
+		node := p.InlineParser.Parse(parent, block, pc)
+		if node != nil {
+			start := segment.Start
+			if line[0] == '!' {
+				start++
+			}
+			pc.Set(linkOpenersKey, append(openers, start))
+		}
+		return node
+	}
+
+	node := p.InlineParser.Parse(parent, block, pc)
+	if len(openers) == 0 {
+		return node
+	}
+	opener := openers[len(openers)-1]
+	pc.Set(linkOpenersKey, openers[:len(openers)-1])
+
+	if node == nil {
+		return nil
+	}
+
+	source := block.Source()
+	closer := segment.Start
+	_, next := block.Position()
+
+	ref := &linkReference{
+		label: source[opener+1 : closer],
+	}
+	switch {
+	case next.Start == closer+1:
+		ref.form = linkShortcut
+	case source[closer+1] == '(':
+		setAttribute(parent, node, inlineLinkAttr, inlineLinkSyntax(source[closer+2:next.Start-1]))
+		return node
+	default:
+		ref.form = linkCollapsed
+		if label := source[closer+2 : next.Start-1]; !util.IsBlank(label) {
+			ref.form = linkFull
+			ref.label = label
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.Link:
+		ref.destination, ref.title = n.Destination, n.Title
+	case *ast.Image:
+		ref.destination, ref.title = n.Destination, n.Title
+	}
+	setAttribute(parent, node, linkReferenceAttr, ref)
+
+	return node
+}
+
+// CloseBlock implements parser.CloseBlocker, openers are never carried over to the next block.
+func (p *linkFormParser) CloseBlock(parent ast.Node, block text.Reader, pc parser.Context) {
+	p.InlineParser.(parser.CloseBlocker).CloseBlock(parent, block, pc)
+	pc.Set(linkOpenersKey, nil)
+}
+
+// linkEnd returns what finishLinkReference writes
+func linkEnd(n ast.Node, text string, destination []byte, title []byte) string {
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	finishLinkReference(w, n, text, destination, title)
+	_ = w.Flush()
+	return b.String()
+}
+
+// finishLinkReference closes a link or an image the way it has been written in the source
+// if it still points to the same definition, and as an inline one otherwise.
+func finishLinkReference(w util.BufWriter, n ast.Node, text string, destination []byte, title []byte) {
+	if v, ok := attribute(n, linkReferenceAttr); ok {
+		ref := v.(*linkReference)
+		if bytes.Equal(ref.destination, destination) && bytes.Equal(ref.title, title) {
+			sameLabel := util.ToLinkReference(ref.label) == util.ToLinkReference([]byte(text))
+			switch {
+			case ref.form == linkShortcut && sameLabel:
+				_ = w.WriteByte(']')
+				return
+			case ref.form == linkCollapsed && sameLabel:
+				_, _ = w.WriteString("][]")
+				return
+			case !bytes.Contains(ref.label, []byte{'\n'}):
+				// the link text has changed (e.g. translated), let's keep the label
+				_, _ = w.WriteString("][")
+				_, _ = w.Write(ref.label)
+				_ = w.WriteByte(']')
+				return
+			}
+		}
+	}
+
+	finishLink(w, n, destination, title)
+}
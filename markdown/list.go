@@ -0,0 +1,75 @@
+package markdown
+
+import (
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ListNumbering is how md2md numbers items of ordered lists.
+type ListNumbering int
+
+const (
+	// ListNumberingSource keeps item numbers as written in the source
+	ListNumberingSource ListNumbering = iota
+
+	// ListNumberingSequential renumbers items as start, start+1, start+2, ...
+	ListNumberingSequential
+
+	// ListNumberingOnes gives every item the start number, i.e. "1." for lists starting at 1
+	ListNumberingOnes
+)
+
+// number of an ordered list item as written in the source, like "01"
+const listItemNumberAttr = "md2md-list-item-number"
+
+// listItemNumberParser wraps parser.NewListItemParser() to save the item number,
+// ast.List keeps the first one only
+type listItemNumberParser struct {
+	parser.BlockParser
+}
+
+func newListItemNumberParser() parser.BlockParser {
+	return &listItemNumberParser{
+		BlockParser: parser.NewListItemParser(),
+	}
+}
+
+func (b *listItemNumberParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	node, state := b.BlockParser.Open(parent, reader, pc)
+	if node == nil || !parent.(*ast.List).IsOrdered() {
+		return node, state
+	}
+
+	i := 0
+	for ; i < len(line) && line[i] == ' '; i++ {
+	}
+	start := i
+	for ; i < len(line) && util.IsNumeric(line[i]); i++ {
+	}
+	setAttribute(parent, node, listItemNumberAttr, line[start:i])
+
+	return node, state
+}
+
+func (r *nodeRenderer) listItemNumber(list *ast.List, item ast.Node) string {
+	switch r.ListNumbering {
+	case ListNumberingOnes:
+		return strconv.Itoa(list.Start)
+	case ListNumberingSource:
+		if v, ok := attribute(item, listItemNumberAttr); ok {
+			return string(v.([]byte))
+		}
+	}
+
+	// sequential, also for items added to the AST
+	index := 0
+	for c := list.FirstChild(); c != nil && c != item; c = c.NextSibling() {
+		index++
+	}
+	return strconv.Itoa(list.Start + index)
+}
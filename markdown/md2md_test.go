@@ -0,0 +1,791 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+func renderMD2MD(t *testing.T, src string, opts ...Option) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := newMarkdown(NewOptions(WithMarkdownOptions(opts...))).Convert([]byte(src), &buf)
+	assert.NoError(t, err)
+	return buf.String()
+}
+
+type md2mdCase struct {
+	name string
+	src  string
+	dst  string
+}
+
+func testMD2MD(t *testing.T, cases []md2mdCase, opts ...Option) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dst := renderMD2MD(t, c.src, opts...)
+			assert.Equal(t, c.dst, dst)
+
+			// the output must be stable
+			assert.Equal(t, c.dst, renderMD2MD(t, dst, opts...))
+		})
+	}
+}
+
+func TestMD2MDTable(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "alignments",
+			src: `| a | b | c | d |
+|---|:--|:-:|--:|
+| 1 | 2 | 3 | 4 |`,
+			dst: `| a | b | c | d |
+| --- | :-- | :-: | --: |
+| 1 | 2 | 3 | 4 |`,
+		},
+		{
+			name: "escaped pipes",
+			src:  "a | b\n--|--\n`x \\| y` | p \\| q",
+			dst:  "| a | b |\n| --- | --- |\n| `x \\| y` | p \\| q |",
+		},
+		{
+			name: "missing cells",
+			src: `| a | b |
+|---|---|
+| 1 |`,
+			dst: `| a | b |
+| --- | --- |
+| 1 |  |`,
+		},
+		{
+			name: "blockquote",
+			src: `> | a | b |
+> |---|---|
+> | *1* | 2 |
+>
+> para`,
+			dst: `> | a | b |
+> | --- | --- |
+> | *1* | 2 |
+>
+> para`,
+		},
+		{
+			name: "list item",
+			src: `- item
+
+  | a |
+  |---|
+  | 1 |`,
+			dst: `- item
+
+  | a |
+  | --- |
+  | 1 |`,
+		},
+	})
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "padding",
+			src: `| left | center | right | none |
+|:--|:-:|--:|---|
+| 1 | 2 | 3 | 4 |`,
+			dst: `| left | center | right | none |
+| :--- | :----: | ----: | ---- |
+| 1    |   2    |     3 | 4    |`,
+		},
+	}, WithTablePadding(true))
+}
+
+func TestMD2MDDefinitionList(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "tight",
+			src: `Apple
+Pomme
+:   Pomaceous fruit
+: Red fruit
+
+Orange
+: Citrus fruit`,
+			dst: `Apple
+Pomme
+: Pomaceous fruit
+: Red fruit
+
+Orange
+: Citrus fruit`,
+		},
+		{
+			name: "loose",
+			src: `Term
+
+: Para 1
+  continuation
+
+  Para 2
+
+: Second description`,
+			dst: `Term
+
+: Para 1
+  continuation
+
+  Para 2
+
+: Second description`,
+		},
+		{
+			name: "nested",
+			src: `> Term
+> : desc
+>
+>   - item 1
+>   - item 2
+
+- Term
+  : desc`,
+			dst: `> Term
+> : desc
+>
+>   - item 1
+>   - item 2
+
+- Term
+  : desc`,
+		},
+	})
+}
+
+func TestMD2MDGFM(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "strikethrough",
+			src:  "~~strike~~ and ~single~",
+			dst:  "~~strike~~ and ~single~",
+		},
+		{
+			name: "task list",
+			src: `- [ ] todo
+- [x] done
+- [X]   done too`,
+			dst: `- [ ] todo
+- [x] done
+- [x] done too`,
+		},
+		{
+			name: "footnotes",
+			src: `Text[^note] and more[^1].
+
+[^1]: The first.
+
+> quote[^note]
+
+[^note]: Named
+    footnote.
+
+    Second para.
+
+[^unused]: Nobody refers to me.`,
+			dst: `Text[^note] and more[^1].
+
+[^1]: The first.
+
+> quote[^note]
+
+[^note]: Named
+    footnote.
+
+    Second para.
+
+[^unused]: Nobody refers to me.`,
+		},
+		{
+			name: "footnotes in containers",
+			src:  "- a[^1]\n\n  [^1]: In an item.\n\n- b\n\n> c[^2]\n> [^2]: In a quote.\n>\n> d\n\n[^3]:",
+			dst:  "- a[^1]\n\n  [^1]: In an item.\n\n- b\n\n> c[^2]\n>\n> [^2]: In a quote.\n>\n> d\n\n[^3]:",
+		},
+	})
+}
+
+func TestMD2MDReferenceLinks(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "forms",
+			src: `[Full][ref], [collapsed][], [shortcut], [inline](/inline "title")
+and ![image][img] with [*emphasis* text][].
+
+[ref]: /full
+[collapsed]: /collapsed 'single quoted'
+[shortcut]:
+  /shortcut
+  "multiline"
+
+Para between.
+
+[img]: /img.png
+[*emphasis* text]: <foo bar>`,
+			dst: `[Full][ref], [collapsed][], [shortcut], [inline](/inline "title")
+and ![image][img] with [*emphasis* text][].
+
+[ref]: /full
+[collapsed]: /collapsed 'single quoted'
+[shortcut]:
+  /shortcut
+  "multiline"
+
+Para between.
+
+[img]: /img.png
+[*emphasis* text]: <foo bar>`,
+		},
+		{
+			name: "definitions before paragraph",
+			src: `> [a]: /a
+> text [a]
+> [b]`,
+			dst: `> [a]: /a
+>
+> text [a]
+> [b]`,
+		},
+		{
+			name: "not a reference",
+			src: `[not a ref] and [foo](bar baz)
+
+[foo]: /foo`,
+			dst: `[not a ref] and [foo](bar baz)
+
+[foo]: /foo`,
+		},
+	})
+}
+
+func TestMD2MDReferenceLinkChanged(t *testing.T) {
+	source := []byte(`[shortcut] and [collapsed][] and [full][ref]
+
+[shortcut]: /a
+[collapsed]: /b
+[ref]: /c`)
+
+	md := newMarkdown(NewOptions())
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*ast.Link); ok && entering {
+			link.ReplaceChild(link, link.FirstChild(), ast.NewString([]byte("translated")))
+			if string(link.Destination) == "/c" {
+				link.Destination = []byte("/changed")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, source, doc))
+	assert.Equal(t, `[translated][shortcut] and [translated][collapsed] and [translated](/changed)
+
+[shortcut]: /a
+[collapsed]: /b
+[ref]: /c`, buf.String())
+}
+
+func TestMD2MDOrderedList(t *testing.T) {
+	src := `1. one
+2. two
+3. three
+
+para
+
+2) two
+2) two
+2) two
+
+para
+
+007. seven
+8. eight`
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "source",
+			src:  src,
+			dst:  src,
+		},
+	})
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "sequential",
+			src:  src,
+			dst: `1. one
+2. two
+3. three
+
+para
+
+2) two
+3) two
+4) two
+
+para
+
+7. seven
+8. eight`,
+		},
+	}, WithListNumbering(ListNumberingSequential))
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "ones",
+			src:  src,
+			dst: `1. one
+1. two
+1. three
+
+para
+
+2) two
+2) two
+2) two
+
+para
+
+7. seven
+7. eight`,
+		},
+	}, WithListNumbering(ListNumberingOnes))
+}
+
+func TestMD2MDStyles(t *testing.T) {
+	src := `Setext 1
+========
+
+Setext 2
+multiline
+---
+
+# ATX 1 {#id}
+
+> Quoted
+> =====
+>
+> - - -
+>
+> ### ATX 3
+
+___
+
+Hard\
+break and spaces  
+break`
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "source",
+			src:  src,
+			dst:  src,
+		},
+	})
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "atx",
+			src:  src,
+			dst: `# Setext 1
+
+## Setext 2 multiline
+
+# ATX 1 {#id}
+
+> # Quoted
+>
+> ---
+>
+> ### ATX 3
+
+---
+
+Hard  
+break and spaces  
+break`,
+		},
+	}, WithHeadingStyle(HeadingStyleATX), WithThematicBreak("---"), WithHardLineBreakStyle(HardLineBreakSpaces))
+
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "setext",
+			src:  src,
+			dst: `Setext 1
+========
+
+Setext 2
+multiline
+---------
+
+ATX 1 {#id}
+===========
+
+> Quoted
+> ======
+>
+> * * *
+>
+> ### ATX 3
+
+* * *
+
+Hard\
+break and spaces\
+break`,
+		},
+	}, WithHeadingStyle(HeadingStyleSetext), WithThematicBreak("* * *"), WithHardLineBreakStyle(HardLineBreakBackslash))
+}
+
+func TestMD2MDEmphasisAndCodeSpans(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "emphasis",
+			src:  "_`code` first_ and *[link](/url) first* and __*nested*__ and ***both*** and _a*b*c_",
+			dst:  "_`code` first_ and *[link](/url) first* and __*nested*__ and ***both*** and _a*b*c_",
+		},
+		{
+			name: "code spans",
+			src:  "`` a`b `` and ``` `` ``` and `` `x` `` and ` ` and `  a  ` and ``plain``",
+			dst:  "`` a`b `` and ``` `` ``` and `` `x` `` and ` ` and `  a  ` and ``plain``",
+		},
+	})
+}
+
+func TestMD2MDCodeSpanChanged(t *testing.T) {
+	md := newMarkdown(NewOptions())
+	src := []byte("`a` and `b`")
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	contents := []string{"x`y", " z "}
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == ast.KindCodeSpan {
+			n.RemoveChildren(n)
+			n.AppendChild(n, ast.NewString([]byte(contents[0])))
+			contents = contents[1:]
+		}
+		return ast.WalkContinue, nil
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+	assert.Equal(t, "``x`y`` and `  z  `", buf.String())
+}
+
+func TestMD2MDFencedCodeBlock(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "long fence",
+			src:  "````markdown\n```go\nfmt.Println()\n```\n````",
+			dst:  "````markdown\n```go\nfmt.Println()\n```\n````",
+		},
+		{
+			name: "tildes and info string",
+			src:  "~~~~ go {linenos=true}\ncode\n~~~~",
+			dst:  "~~~~ go {linenos=true}\ncode\n~~~~",
+		},
+		{
+			name: "indented",
+			src:  "  ```\n  a\n    b\n\n  ```",
+			dst:  "  ```\n  a\n    b\n\n  ```",
+		},
+		{
+			name: "nested",
+			src:  "> - ~~~\n>   code\n>   ~~~\n>\n>   text",
+			dst:  "> - ~~~\n>   code\n>   ~~~\n>\n>   text",
+		},
+	})
+}
+
+func TestMD2MDFencedCodeBlockChanged(t *testing.T) {
+	md := newMarkdown(NewOptions())
+	src := []byte("```go\ncode\n```\n")
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	// the content gets a line which would close the source fence
+	extra := []byte("```\n")
+	block := doc.FirstChild()
+	block.Lines().Append(text.NewSegment(len(src), len(src)+len(extra)))
+	src = append(src, extra...)
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+	assert.Equal(t, "````go\ncode\n```\n````", buf.String())
+}
+
+func TestMD2MDEscapeInjectedText(t *testing.T) {
+	cases := []struct {
+		name   string
+		src    string
+		inject string
+		dst    string
+	}{
+		{"inline", "x", `*a* _b_ snake_case [c] ` + "`d`" + ` ~e~ <div> a < b &copy; & \* \ c`,
+			`\*a\* \_b\_ snake_case \[c\] ` + "\\`d\\`" + ` \~e\~ \<div> a < b \&copy; & \\\* \ c`},
+		{"line start", "x", "# a\n1. b\n- c\n> d\n---\n: e\n1 f\n#hashtag", "\\# a\n1\\. b\n\\- c\n\\> d\n\\---\n\\: e\n1 f\n#hashtag"},
+		{"not line start", "*x*", "# a", "*# a*"},
+		{"heading end", "# x", "C# {#id} ##", "# C# {#id} \\##"},
+		{"heading attributes", "# x", "a {#id}", "# a \\{#id}"},
+		{"hard break", "x", "a  \nb\\", "a\nb\\\\"},
+		{"quote", "> x", "a\n- b", "> a\n> \\- b"},
+		{"indented", "x", "    a\n\tb\n  - c", "a\nb\n\\- c"},
+		{"indented in list", "- x", "    a\n\tb", "- a\n  b"},
+		{"table cell", "| x |\n|---|\n| y |", `a\|b | c`, "| a\\\\\\|b \\| c |\n| --- |\n| y |"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			md := newMarkdown(NewOptions())
+			src := []byte(c.src)
+			doc := md.Parser().Parse(text.NewReader(src))
+
+			// the innermost inline parent of the text gets the injected one instead
+			var parent ast.Node
+			_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+				if entering && n.Kind() == ast.KindText {
+					parent = n.Parent()
+					return ast.WalkStop, nil
+				}
+				return ast.WalkContinue, nil
+			})
+			parent.RemoveChildren(parent)
+			parent.AppendChild(parent, ast.NewString([]byte(c.inject)))
+
+			var buf bytes.Buffer
+			assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+			assert.Equal(t, c.dst, buf.String())
+		})
+	}
+}
+
+func TestMD2MDEscapeRoundTrip(t *testing.T) {
+	inject := "# *a* _b_ [c](d) `e` ~f~ <g> &amp; \\ h  \n1) i\n+ j\n===\n<!-- k -->"
+
+	md := newMarkdown(NewOptions())
+	src := []byte("x")
+	doc := md.Parser().Parse(text.NewReader(src))
+	paragraph := doc.FirstChild()
+	paragraph.RemoveChildren(paragraph)
+	paragraph.AppendChild(paragraph, ast.NewString([]byte(inject)))
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+
+	// the output must be a single paragraph of the very text
+	doc = md.Parser().Parse(text.NewReader(buf.Bytes()))
+	assert.Equal(t, 1, doc.ChildCount())
+	assert.Equal(t, ast.KindParagraph, doc.FirstChild().Kind())
+	for c := doc.FirstChild().FirstChild(); c != nil; c = c.NextSibling() {
+		assert.Equal(t, ast.KindText, c.Kind())
+	}
+}
+
+func TestMD2MDFrontMatter(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "yaml",
+			src:  "---\ntitle: Hello  \ntags: [a, b]\n---\n# Heading\n\ntext",
+			dst:  "---\ntitle: Hello  \ntags: [a, b]\n---\n# Heading\n\ntext",
+		},
+		{
+			name: "toml",
+			src:  "+++\ntitle = \"Hello\"\n+++\n\ntext",
+			dst:  "+++\ntitle = \"Hello\"\n+++\n\ntext",
+		},
+		{
+			name: "not closed",
+			src:  "---\ntitle: Hello",
+			dst:  "---\n\ntitle: Hello",
+		},
+		{
+			name: "not first",
+			src:  "text\n\n---\ntitle: Hello\n---",
+			dst:  "text\n\n---\n\ntitle: Hello\n---",
+		},
+	})
+}
+
+func TestFrontMatter(t *testing.T) {
+	src := []byte("---\ntitle: Hello\ndescription: World\n---\n# Heading\n")
+
+	var buf bytes.Buffer
+	assert.NoError(t, Convert(src, &buf, WithFormat(FormatHTML)))
+	assert.Equal(t, "<h1 id=\"heading\">Heading</h1>\n", buf.String())
+
+	fields, err := ParseFrontMatter(src)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Hello", "description": "World"}, fields)
+
+	fields, err = ParseFrontMatter([]byte("+++\ntitle = \"Hello\"\n+++\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Hello"}, fields)
+
+	fields, err = ParseFrontMatter([]byte("# Heading\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+
+	_, err = ParseFrontMatter([]byte("---\n: :\n---\n"))
+	assert.Error(t, err)
+
+	md := newMarkdown(NewOptions())
+	doc := md.Parser().Parse(text.NewReader(src))
+	fm := GetFrontMatter(doc)
+	if assert.NotNil(t, fm) {
+		assert.Equal(t, FrontMatterYAML, fm.Format)
+		fields, err = fm.Fields(src)
+		assert.NoError(t, err)
+		assert.Equal(t, "World", fields["description"])
+	}
+}
+
+func TestMD2MDAutoLinks(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "angle",
+			src:  "<https://example.com> and <foo@bar.com> and <ftp://example.com/a>",
+			dst:  "<https://example.com> and <foo@bar.com> and <ftp://example.com/a>",
+		},
+		{
+			name: "linkify",
+			src:  "https://example.com and foo@bar.com and www.example.com",
+			dst:  "https://example.com and foo@bar.com and www.example.com",
+		},
+	})
+}
+
+func TestMD2MDLinkDestinationAndTitle(t *testing.T) {
+	testMD2MD(t, []md2mdCase{
+		{
+			name: "source syntax",
+			src:  `[a](<b c> "d") [e](f 'g "h"') [i](j (k)) [l](<m>) [n](o(p) "q \" r") ![s](<t u>)`,
+			dst:  `[a](<b c> "d") [e](f 'g "h"') [i](j (k)) [l](<m>) [n](o(p) "q \" r") ![s](<t u>)`,
+		},
+		{
+			name: "empty",
+			src:  `[a]() [b](<>) [c](<> "d")`,
+			dst:  `[a]() [b](<>) [c](<> "d")`,
+		},
+	})
+}
+
+func TestMD2MDLinkChanged(t *testing.T) {
+	md := newMarkdown(NewOptions())
+	src := []byte(`[a](/a 'x') [b](/b (y)) [c](/c) ![d](/d)`)
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	type change struct {
+		destination string
+		title       string
+	}
+	changes := []change{
+		{"/a b", `it's`},
+		{"/b(", "(z)"},
+		{"<c>", `"'()`},
+		{"/d)", ""},
+	}
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var destination, title *[]byte
+		switch n := n.(type) {
+		case *ast.Link:
+			destination, title = &n.Destination, &n.Title
+		case *ast.Image:
+			destination, title = &n.Destination, &n.Title
+		default:
+			return ast.WalkContinue, nil
+		}
+		c := changes[0]
+		changes = changes[1:]
+		*destination = []byte(c.destination)
+		*title = nil
+		if c.title != "" {
+			*title = []byte(c.title)
+		}
+		return ast.WalkContinue, nil
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+	assert.Equal(t, `[a](</a b> "it's") [b](</b(> "(z)") [c](<\<c\>> "\"'()") ![d](</d)>)`, buf.String())
+}
+
+func TestMD2MDKeepSource(t *testing.T) {
+	src := "Title\n=====\n\n\n*   one\n*   two\n\n> quote  \n> ```\n> code\n>\n> ```\n>\n> para *x*\n> lazy\n\n" +
+		"| a |  b |\n|---|----|\n| 1 | 2 |\n\n1)  x\n\n    y\n2)  z\n"
+
+	// nothing changed
+	assert.Equal(t, src, renderMD2MD(t, src, WithKeepSource(true)))
+	assert.NotEqual(t, src, renderMD2MD(t, src))
+
+	md := newMarkdown(NewOptions(WithMarkdownOptions(WithKeepSource(true))))
+	doc := md.Parser().Parse(text.NewReader([]byte(src)))
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); entering && ok {
+			switch string(t.Segment.Value([]byte(src))) {
+			case "para ":
+				p := n.Parent()
+				p.ReplaceChild(p, n, ast.NewString([]byte("new ")))
+				MarkChanged(p)
+			case "z":
+				MarkChanged(n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	assert.NoError(t, err)
+
+	// only the changed paragraph and list item are rendered, their containers keep source prefixes
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, []byte(src), doc))
+	assert.Equal(t, "Title\n=====\n\n\n*   one\n*   two\n\n> quote  \n> ```\n> code\n>\n> ```\n>\n> new *x*\n> lazy\n\n"+
+		"| a |  b |\n|---|----|\n| 1 | 2 |\n\n1)  x\n\n    y\n2)  z\n", buf.String())
+}
+
+func TestMD2MDKeepSourceAddedBlock(t *testing.T) {
+	src := []byte("# Head\n\n-  a\n\n   b\n")
+	md := newMarkdown(NewOptions(WithMarkdownOptions(WithKeepSource(true))))
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	// blocks added without MarkChanged are rendered too
+	item := doc.LastChild().FirstChild()
+	item.AppendChild(item, ast.NewThematicBreak())
+
+	var buf bytes.Buffer
+	assert.NoError(t, md.Renderer().Render(&buf, src, doc))
+	assert.Equal(t, "# Head\n\n-  a\n\n   b\n\n   ***\n", buf.String())
+}
+
+func TestMD2MDKeepSourceSplice(t *testing.T) {
+	// containers and tables keep their source around a changed block, found without MarkChanged
+	cases := []struct {
+		name     string
+		src, dst string
+	}{
+		{"list item", "*   item one\n*   item two\n", "*   item one\n*   item 2\n"},
+		{"blockquote in list", "- > a\n  > b two\n", "- > a\n  > b 2\n"},
+		{"definition", "term\n\n:   def one\n:   def two\n", "term\n\n:   def one\n:   def 2\n"},
+		{"footnote", "x[^note]\n\n[^note]: Named  two\n", "x[^note]\n\n[^note]: Named  2\n"},
+		{"table cell", "| a |  b  |\n|---|-----|\n| 1 | two |\n| 3 |  4  |\n", "| a |  b  |\n|---|-----|\n| 1 | 2 |\n| 3 |  4  |\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.dst, convertMD2MD(t, c.src,
+				WithTransformers(ReplaceText("two", "2")), WithMarkdownOptions(WithKeepSource(true))))
+		})
+	}
+}
@@ -4,28 +4,42 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/util"
-
-	utilerr "git.catbo.net/muravjov/go2023/util"
 )
 
+// nodeRenderer renders CommonMark nodes, extension node renderers are built on it
+// to share the Context and its Config
 type nodeRenderer struct {
+	*Config
+
 	context *Context
 }
 
-func NewNodeRenderer(context *Context) NodeRenderer {
-	r := &nodeRenderer{
+func newNodeRenderer(context *Context) *nodeRenderer {
+	return &nodeRenderer{
+		Config:  &context.Config,
 		context: context,
 	}
+}
 
-	return r
+// NewNodeRenderer returns the node renderer of CommonMark nodes, opts are applied
+// to the Config of the context.
+func NewNodeRenderer(context *Context, opts ...Option) NodeRenderer {
+	for _, opt := range opts {
+		opt(&context.Config)
+	}
+	return newNodeRenderer(context)
 }
 
 func (r *nodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
 	// blocks
 
+	reg.Register(ast.KindDocument, r.renderChildren)
+	reg.Register(ast.KindParagraph, r.renderChildren)
+	reg.Register(ast.KindTextBlock, r.renderChildren)
 	reg.Register(ast.KindHeading, r.renderHeading)
 	reg.Register(ast.KindBlockquote, r.renderBlockquote)
 	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
@@ -34,6 +48,7 @@ func (r *nodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindList, r.renderList)
 	reg.Register(ast.KindListItem, r.renderListItem)
 	reg.Register(ast.KindThematicBreak, r.renderThematicBreak)
+	reg.Register(KindLinkReferenceDefinitions, r.renderLinkReferenceDefinitions)
 
 	// inlines
 
@@ -45,49 +60,95 @@ func (r *nodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindRawHTML, r.renderRawHTML)
 	reg.Register(ast.KindText, r.renderText)
 	reg.Register(ast.KindString, r.renderString)
-
 }
 
 var attrNameID = []byte("id")
 var attrNameClass = []byte("class")
 
+func writeHeadingAttributes(w util.BufWriter, node ast.Node) {
+	attributes := node.Attributes()
+	if attributes == nil {
+		return
+	}
+
+	_, _ = w.WriteString(" {")
+	first := true
+	for _, attr := range attributes {
+		if !first {
+			w.WriteByte(' ')
+		}
+		first = false
+
+		if bytes.Equal(attr.Name, attrNameID) {
+			w.WriteByte('#')
+		} else if bytes.Equal(attr.Name, attrNameClass) {
+			w.WriteByte('.')
+		} else {
+			_, _ = w.Write(attr.Name)
+			w.WriteByte('=')
+		}
+
+		var value []byte
+		switch typed := attr.Value.(type) {
+		case []byte:
+			value = typed
+		case string:
+			value = util.StringToReadOnlyBytes(typed)
+		}
+		_, _ = w.Write(value)
+	}
+	_, _ = w.WriteString("}")
+}
+
 func (r *nodeRenderer) renderHeading(
 	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
 	n := node.(*ast.Heading)
-	if entering {
+
+	content, err := r.context.RenderToString(w, func(w util.BufWriter) error {
+		return r.context.RenderChildren(w, source, n)
+	})
+	if err != nil {
+		return ast.WalkStop, err
+	}
+
+	attributes, _ := r.context.RenderToString(w, func(w util.BufWriter) error {
+		writeHeadingAttributes(w, n)
+		return nil
+	})
+	content += attributes
+
+	if r.headingStyle(source, n) == HeadingStyleATX {
+		// ATX heading is a single line
+		content = strings.ReplaceAll(content, "\n"+r.context.padding(w), " ")
+
 		_, _ = w.WriteString(strings.Repeat("#", n.Level) + " ")
-	} else {
-		if node.Attributes() != nil {
-			_, _ = w.WriteString(" {")
-			first := true
-			for _, attr := range node.Attributes() {
-				if !first {
-					w.WriteByte(' ')
-				}
-				first = false
-
-				if bytes.Equal(attr.Name, attrNameID) {
-					w.WriteByte('#')
-				} else if bytes.Equal(attr.Name, attrNameClass) {
-					w.WriteByte('.')
-				} else {
-					_, _ = w.Write(attr.Name)
-					w.WriteByte('=')
-				}
-
-				var value []byte
-				switch typed := attr.Value.(type) {
-				case []byte:
-					value = typed
-				case string:
-					value = util.StringToReadOnlyBytes(typed)
-				}
-				_, _ = w.Write(value)
-			}
-			_, _ = w.WriteString("}")
+		_, _ = w.WriteString(content)
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString(content)
+
+	underline := "="
+	if n.Level == 2 {
+		underline = "-"
+	}
+	length := setextUnderlineLength(source, n)
+	if r.HeadingStyle != HeadingStyleSource || length == 0 {
+		lastLine := content
+		if i := strings.LastIndexByte(content, '\n'); i >= 0 {
+			lastLine = strings.TrimPrefix(content[i+1:], r.context.padding(w))
 		}
+		length = max(3, utf8.RuneCountInString(lastLine))
 	}
-	return ast.WalkContinue, nil
+
+	_ = w.WriteByte('\n')
+	r.context.Pad(w)
+	_, _ = w.WriteString(strings.Repeat(underline, length))
+
+	return ast.WalkSkipChildren, nil
 }
 
 func (r *nodeRenderer) renderBlockquote(
@@ -95,9 +156,9 @@ func (r *nodeRenderer) renderBlockquote(
 	if entering {
 		_, _ = w.WriteString("> ")
 
-		r.context.PushStack("> ")
+		r.context.PushStack(w, "> ")
 	} else {
-		r.context.PopStack()
+		r.context.PopStack(w)
 	}
 	return ast.WalkContinue, nil
 }
@@ -126,49 +187,6 @@ func (r *nodeRenderer) renderCodeBlock(w util.BufWriter, source []byte, n ast.No
 	return ast.WalkContinue, nil
 }
 
-func (r *nodeRenderer) renderFencedCodeBlock(
-	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
-	n := node.(*ast.FencedCodeBlock)
-
-	fenceMarker := "```"
-	lines := n.Lines()
-	if l := lines.Len(); l > 0 {
-		pos := lines.At(l - 1).Stop
-		// quoting:
-		// The closing code fence may be preceded by up to three spaces of indentation, and may be followed only by spaces or tabs, which are ignored.
-		for i := pos; i < len(source); i++ {
-			if source[i] == ' ' {
-				continue
-			}
-
-			if i-pos > 3 {
-				break
-			}
-
-			if source[i] != '~' {
-				break
-			}
-
-			fenceMarker = "~~~"
-		}
-	}
-
-	if entering {
-		_, _ = w.WriteString(fenceMarker)
-		language := n.Language(source)
-		if language != nil {
-			w.Write(language)
-		}
-		_, _ = w.WriteString("\n")
-		r.context.Pad(w)
-
-		r.writeLines(w, source, n, false)
-	} else {
-		_, _ = w.WriteString(fenceMarker)
-	}
-	return ast.WalkContinue, nil
-}
-
 func (r *nodeRenderer) renderHTMLBlock(
 	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.HTMLBlock)
@@ -191,6 +209,12 @@ func (r *nodeRenderer) renderHTMLBlock(
 	return ast.WalkContinue, nil
 }
 
+// renderChildren is for nodes without syntax of their own, Renderer separates blocks
+func (r *nodeRenderer) renderChildren(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
 func (r *nodeRenderer) renderList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	//n := node.(*ast.List)
 	return ast.WalkContinue, nil
@@ -199,37 +223,42 @@ func (r *nodeRenderer) renderList(w util.BufWriter, source []byte, node ast.Node
 func (r *nodeRenderer) renderListItem(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	list, ok := n.Parent().(*ast.List)
 	if !ok {
-		utilerr.Errorf("expected list node but got: %v", n.Parent().Kind().String())
-		return ast.WalkStop, nil
+		return ast.WalkStop, fmt.Errorf("expected list node but got: %v", n.Parent().Kind())
 	}
 
 	if entering {
 		var prefix string
+		sourceWidth := 2
 		if list.IsOrdered() {
-			prefix = fmt.Sprintf("%v%c ", list.Start, list.Marker)
+			prefix = fmt.Sprintf("%v%c ", r.listItemNumber(list, n), list.Marker)
+			sourceWidth = len(prefix)
+			if v, ok := attribute(n, listItemNumberAttr); ok {
+				sourceWidth = len(v.([]byte)) + 2
+			}
 		} else {
 			prefix = string(list.Marker) + " "
 		}
 
-		if diff := n.(*ast.ListItem).Offset - len(prefix); diff > 0 {
+		if diff := n.(*ast.ListItem).Offset - sourceWidth; diff > 0 {
 			// golang docs style: if offset more then just prefix, let's pad it with spaces at left
 			prefix = strings.Repeat(" ", diff) + prefix
 		}
 
 		_, _ = w.WriteString(prefix)
 
-		r.context.PushStack(strings.Repeat(" ", len(prefix)))
+		r.context.PushStack(w, strings.Repeat(" ", len(prefix)))
 	} else {
-		r.context.PopStack()
+		r.context.PopStack(w)
 	}
 	return ast.WalkContinue, nil
 }
 
-func (r *nodeRenderer) renderThematicBreak(w util.BufWriter, _ []byte, _ ast.Node, entering bool) (ast.WalkStatus, error) {
+func (r *nodeRenderer) renderThematicBreak(w util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
 		return ast.WalkContinue, nil
 	}
-	_, _ = w.WriteString("***\n")
+	_, _ = w.Write(r.thematicBreak(n))
+	_ = w.WriteByte('\n')
 
 	return ast.WalkContinue, nil
 }
@@ -239,7 +268,13 @@ func (r *nodeRenderer) renderAutoLink(w util.BufWriter, source []byte, node ast.
 		return ast.WalkContinue, nil
 	}
 	n := node.(*ast.AutoLink)
-	_, _ = w.Write(n.Label(source))
+	if isAngleAutoLink(n) {
+		_ = w.WriteByte('<')
+		_, _ = w.Write(n.Label(source))
+		_ = w.WriteByte('>')
+	} else {
+		_, _ = w.Write(n.Label(source))
+	}
 	return ast.WalkContinue, nil
 }
 
@@ -247,9 +282,12 @@ func (r *nodeRenderer) renderCodeSpan(w util.BufWriter, source []byte, n ast.Nod
 	if !entering {
 		return ast.WalkContinue, nil
 	}
-	_ = w.WriteByte('`')
-	r.renderTexts(w, source, n)
-	_ = w.WriteByte('`')
+	content, _ := r.context.RenderToString(w, func(w util.BufWriter) error {
+		r.renderTexts(w, source, n)
+		return nil
+	})
+
+	_, _ = w.WriteString(codeSpanMarkdown(source, n, content))
 	return ast.WalkSkipChildren, nil
 }
 
@@ -257,42 +295,22 @@ func (r *nodeRenderer) renderEmphasis(
 	w util.BufWriter, source []byte, node ast.Node, _ bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Emphasis)
 
-	// * or _
-	marker := "*"
-
-	// :TRICKY: better to support .Marker attribute in ast.Emphasis struct, saving it
-	// in last successful emphasisDelimiterProcessor.CanOpenCloser() from opener.Char and
-	// initialising it in emphasisDelimiterProcessor.OnMatch()
-	if t, ok := n.FirstChild().(*ast.Text); ok {
-		// * or _ should not be followed by whitespace, let's get the previous character
-		if pos := t.Segment.Start - 1; pos >= 0 && pos < len(source) {
-			marker = string(source[pos])
-		}
-	}
-
-	_, _ = w.WriteString(strings.Repeat(marker, n.Level))
+	_, _ = w.WriteString(strings.Repeat(string(emphasisMarker(n)), n.Level))
 	return ast.WalkContinue, nil
 }
 
-func finishLink(w util.BufWriter, destination []byte, title []byte) {
-	_, _ = w.WriteString("](")
-	w.Write(destination)
-	if title != nil {
-		_, _ = w.WriteString(` "`)
-		_, _ = w.Write(title)
-		_ = w.WriteByte('"')
-	}
-	_ = w.WriteByte(')')
-}
-
 func (r *nodeRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
 		return ast.WalkContinue, nil
 	}
 	n := node.(*ast.Image)
+	alt, _ := r.context.RenderToString(w, func(w util.BufWriter) error {
+		r.renderTexts(w, source, n)
+		return nil
+	})
 	_, _ = w.WriteString("![")
-	r.renderTexts(w, source, n)
-	finishLink(w, n.Destination, n.Title)
+	_, _ = w.WriteString(alt)
+	finishLinkReference(w, n, alt, n.Destination, n.Title)
 	return ast.WalkSkipChildren, nil
 }
 
@@ -301,7 +319,11 @@ func (r *nodeRenderer) renderString(w util.BufWriter, _ []byte, node ast.Node, e
 		return ast.WalkContinue, nil
 	}
 	n := node.(*ast.String)
-	_, _ = w.Write(n.Value)
+	if n.IsRaw() || n.IsCode() || inCode(n) {
+		rawWrite(w, n.Value, r.context)
+	} else {
+		rawWrite(w, escapeText(n.Value, placementOf(n)), r.context)
+	}
 	return ast.WalkContinue, nil
 }
 
@@ -318,13 +340,21 @@ func (r *nodeRenderer) renderTexts(w util.BufWriter, source []byte, n ast.Node)
 }
 
 func (r *nodeRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
 	n := node.(*ast.Link)
-	if entering {
-		_, _ = w.WriteString("[")
-	} else {
-		finishLink(w, n.Destination, n.Title)
+	// the link text is needed to decide if a reference link can stay as is
+	text, err := r.context.RenderToString(w, func(w util.BufWriter) error {
+		return r.context.RenderChildren(w, source, n)
+	})
+	if err != nil {
+		return ast.WalkStop, err
 	}
-	return ast.WalkContinue, nil
+	_, _ = w.WriteString("[")
+	_, _ = w.WriteString(text)
+	finishLinkReference(w, n, text, n.Destination, n.Title)
+	return ast.WalkSkipChildren, nil
 }
 
 func (r *nodeRenderer) renderRawHTML(
@@ -371,8 +401,7 @@ func (r *nodeRenderer) renderText(w util.BufWriter, source []byte, node ast.Node
 	}
 
 	if n.HardLineBreak() {
-		_, _ = w.WriteString(`\
-`)
+		_, _ = w.WriteString(r.hardLineBreak(source, n))
 		r.context.Pad(w)
 	} else if n.SoftLineBreak() {
 		_, _ = w.WriteString("\n")
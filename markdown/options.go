@@ -0,0 +1,152 @@
+package markdown
+
+import (
+	"io"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Format is an output format of Convert.
+type Format int
+
+const (
+	// FormatMarkdown is md2md
+	FormatMarkdown Format = iota
+
+	// FormatHTML is md2html
+	FormatHTML
+
+	// FormatText is plain text without any markup
+	FormatText
+)
+
+// Default priorities of node renderers Convert goes with; extra node renderers with lower
+// priority values take precedence over them.
+const (
+	MarkdownNodeRendererPriority = 400
+	HTMLNodeRendererPriority     = 1000
+	TextNodeRendererPriority     = 100
+)
+
+// Options holds Convert options.
+type Options struct {
+	// Format is the output format
+	Format Format
+
+	// Extensions are added after the default ones: definition lists, tables, strikethrough,
+	// task lists, footnotes, front matter and linkify
+	Extensions []goldmark.Extender
+
+	// LinkifyProtocols are URL schemes which linkify turns into links, "http" and "https" by default
+	LinkifyProtocols []string
+
+	// ParserOptions are applied to the parser, like extra AST transformers
+	ParserOptions []parser.Option
+
+	// NodeRenderers are markdown.NodeRenderer for FormatMarkdown
+	NodeRenderers []util.PrioritizedValue
+
+	// GoldmarkNodeRenderers are goldmark renderer.NodeRenderer for FormatHTML and FormatText
+	GoldmarkNodeRenderers []util.PrioritizedValue
+
+	// Transformers change the document before it is rendered, in their order
+	Transformers []Transformer
+
+	// MarkdownOptions configure md2md rendering
+	MarkdownOptions []Option
+
+	// Dump is where the AST is dumped to, nil means no dump
+	Dump io.Writer
+
+	// VerbosePadding numbers every padding in md2md output, for debugging
+	VerbosePadding bool
+}
+
+// ConvertOption is a functional option for Convert.
+type ConvertOption func(*Options)
+
+// NewOptions returns default Options with opts applied.
+func NewOptions(opts ...ConvertOption) Options {
+	o := Options{
+		Format:           FormatMarkdown,
+		LinkifyProtocols: []string{"http", "https"},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithFormat sets the output format.
+func WithFormat(v Format) ConvertOption {
+	return func(o *Options) {
+		o.Format = v
+	}
+}
+
+// WithExtensions adds goldmark extensions.
+func WithExtensions(v ...goldmark.Extender) ConvertOption {
+	return func(o *Options) {
+		o.Extensions = append(o.Extensions, v...)
+	}
+}
+
+// WithLinkifyProtocols sets URL schemes which linkify turns into links.
+func WithLinkifyProtocols(v ...string) ConvertOption {
+	return func(o *Options) {
+		o.LinkifyProtocols = v
+	}
+}
+
+// WithParserOptions adds parser options.
+func WithParserOptions(v ...parser.Option) ConvertOption {
+	return func(o *Options) {
+		o.ParserOptions = append(o.ParserOptions, v...)
+	}
+}
+
+// WithNodeRenderer adds an md2md node renderer with the priority, see Options.NodeRenderers.
+func WithNodeRenderer(v NodeRenderer, priority int) ConvertOption {
+	return func(o *Options) {
+		o.NodeRenderers = append(o.NodeRenderers, util.Prioritized(v, priority))
+	}
+}
+
+// WithGoldmarkNodeRenderer adds a goldmark node renderer with the priority,
+// see Options.GoldmarkNodeRenderers.
+func WithGoldmarkNodeRenderer(v renderer.NodeRenderer, priority int) ConvertOption {
+	return func(o *Options) {
+		o.GoldmarkNodeRenderers = append(o.GoldmarkNodeRenderers, util.Prioritized(v, priority))
+	}
+}
+
+// WithTransformers adds transformers of the document.
+func WithTransformers(v ...Transformer) ConvertOption {
+	return func(o *Options) {
+		o.Transformers = append(o.Transformers, v...)
+	}
+}
+
+// WithMarkdownOptions adds md2md rendering options.
+func WithMarkdownOptions(v ...Option) ConvertOption {
+	return func(o *Options) {
+		o.MarkdownOptions = append(o.MarkdownOptions, v...)
+	}
+}
+
+// WithDump dumps the AST to w.
+func WithDump(w io.Writer) ConvertOption {
+	return func(o *Options) {
+		o.Dump = w
+	}
+}
+
+// WithVerbosePadding numbers every padding in md2md output.
+func WithVerbosePadding(v bool) ConvertOption {
+	return func(o *Options) {
+		o.VerbosePadding = v
+	}
+}
@@ -0,0 +1,86 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+// md2mdParser tells the parser made by NewParser from others
+type md2mdParser struct {
+	parser.Parser
+}
+
+// NewParser returns goldmark default parser where links, autolinks, emphases, link reference definitions,
+// list items, thematic breaks and fenced code blocks are parsed with wrappers which keep source details for md2md;
+// all blocks, of extensions too, keep their source rows for Config.KeepSource
+func NewParser() parser.Parser {
+	inlineParsers := parser.DefaultInlineParsers()
+	for i, v := range inlineParsers {
+		switch v.Value {
+		case parser.NewLinkParser():
+			inlineParsers[i].Value = newLinkFormParser()
+		case parser.NewEmphasisParser():
+			inlineParsers[i].Value = newEmphasisMarkerParser()
+		case parser.NewAutoLinkParser():
+			inlineParsers[i].Value = newAutoLinkFormParser()
+		}
+	}
+
+	paragraphTransformers := parser.DefaultParagraphTransformers()
+	for i, v := range paragraphTransformers {
+		if v.Value == parser.LinkReferenceParagraphTransformer {
+			paragraphTransformers[i].Value = &linkReferenceDefinitionsTransformer{}
+		}
+	}
+
+	blockParsers := parser.DefaultBlockParsers()
+	for i, v := range blockParsers {
+		switch v.Value {
+		case parser.NewListItemParser():
+			blockParsers[i].Value = newListItemNumberParser()
+		case parser.NewThematicBreakParser():
+			blockParsers[i].Value = newThematicBreakParser()
+		case parser.NewFencedCodeBlockParser():
+			blockParsers[i].Value = newFencedCodeBlockParser()
+		}
+	}
+
+	return &md2mdParser{
+		Parser: parser.NewParser(
+			parser.WithBlockParsers(sourceBlockParsers(blockParsers)...),
+			parser.WithInlineParsers(inlineParsers...),
+			parser.WithParagraphTransformers(sourceParagraphTransformers(paragraphTransformers)...),
+			parser.WithASTTransformers(util.Prioritized(mdTransformFunc(saveFingerprints), saveFingerprintsPriority)),
+		),
+	}
+}
+
+// AddOptions wraps block parsers and paragraph transformers of extensions to save source rows,
+// see sourceBlockParser, and the strikethrough parser to keep its delimiter
+func (p *md2mdParser) AddOptions(opts ...parser.Option) {
+	config := parser.NewConfig()
+	for _, opt := range opts {
+		opt.SetParserOption(config)
+	}
+	for i, v := range config.InlineParsers {
+		if v.Value == extension.NewStrikethroughParser() {
+			config.InlineParsers[i].Value = newStrikethroughLengthParser()
+		}
+	}
+
+	var options []parser.Option
+	for name, value := range config.Options {
+		options = append(options, parser.WithOption(name, value))
+	}
+	if config.EscapedSpace {
+		options = append(options, parser.WithEscapedSpace())
+	}
+	options = append(options,
+		parser.WithBlockParsers(sourceBlockParsers(config.BlockParsers)...),
+		parser.WithInlineParsers(config.InlineParsers...),
+		parser.WithParagraphTransformers(sourceParagraphTransformers(config.ParagraphTransformers)...),
+		parser.WithASTTransformers(config.ASTTransformers...),
+	)
+	p.Parser.AddOptions(options...)
+}
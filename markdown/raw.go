@@ -0,0 +1,135 @@
+package markdown
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Nodes without NodeRendererFunc, e.g. of third-party extensions, are copied from the source
+// as is. Their own syntax isn't in segments, so it is guessed: a top-level block takes whole
+// lines, an inline takes what is between it and its text siblings.
+
+// rawRange returns the source range of n, or -1 if it is unknown
+func rawRange(source []byte, n ast.Node) (int, int) {
+	start, stop := nodeOffset(n), nodeStop(n)
+
+	parent := n.Parent()
+	if n.Type() == ast.TypeBlock {
+		if start >= 0 && parent != nil && parent.Kind() == ast.KindDocument {
+			// no container prefix, so lines are the block's
+			start = bytes.LastIndexByte(source[:start], '\n') + 1
+			if i := bytes.IndexByte(source[stop:], '\n'); i >= 0 {
+				stop += i
+			} else {
+				stop = len(source)
+			}
+		}
+		return start, trimRightSpace(source, start, stop)
+	}
+
+	if prev, ok := n.PreviousSibling().(*ast.Text); ok {
+		// the line break after a text is the text's one
+		if !prev.SoftLineBreak() && !prev.HardLineBreak() {
+			start = prev.Segment.Stop
+		} else if starts := lineStarts(n); start >= 0 {
+			// n starts a line
+			i, found := slices.BinarySearch(starts, start)
+			if !found && i > 0 {
+				i--
+			}
+			if i < len(starts) && starts[i] <= start {
+				start = starts[i]
+			}
+		}
+	} else if n.PreviousSibling() == nil && parent != nil && parent.Type() == ast.TypeBlock && parent.Lines().Len() > 0 {
+		start = parent.Lines().At(0).Start
+	}
+	if next, ok := n.NextSibling().(*ast.Text); ok {
+		stop = next.Segment.Start
+	} else if n.NextSibling() == nil && parent != nil && parent.Type() == ast.TypeBlock && parent.Lines().Len() > 0 {
+		stop = parent.Lines().At(parent.Lines().Len() - 1).Stop
+	}
+	if start < 0 || stop < start {
+		return -1, -1
+	}
+	return start, trimRightSpace(source, start, stop)
+}
+
+// nodeStop is the end of the node text in the source, or -1, see nodeOffset()
+func nodeStop(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Stop
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 && !n.HasChildren() {
+		return n.Lines().At(n.Lines().Len() - 1).Stop
+	}
+	for c := n.LastChild(); c != nil; c = c.PreviousSibling() {
+		if stop := nodeStop(c); stop >= 0 {
+			return stop
+		}
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(n.Lines().Len() - 1).Stop
+	}
+	return -1
+}
+
+func trimRightSpace(source []byte, start, stop int) int {
+	for stop > start && util.IsSpace(source[stop-1]) {
+		stop--
+	}
+	return stop
+}
+
+// lineStarts returns where the content of lines of n starts, after prefixes of containers
+func lineStarts(n ast.Node) []int {
+	block := n
+	for block != nil && block.Type() != ast.TypeBlock {
+		block = block.Parent()
+	}
+
+	var starts []int
+	_ = ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Type() == ast.TypeBlock {
+			for i := 0; i < n.Lines().Len(); i++ {
+				starts = append(starts, n.Lines().At(i).Start)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	slices.Sort(starts)
+	return starts
+}
+
+// writeRaw writes source[start:stop] of n with the current padding instead of the source one
+func (r *Renderer) writeRaw(w util.BufWriter, source []byte, n ast.Node, start, stop int) {
+	padded := len(r.context.PaddingStack(w)) > 0
+	starts := lineStarts(n)
+
+	for pos := start; pos < stop; {
+		i := bytes.IndexByte(source[pos:stop], '\n')
+		if i < 0 {
+			_, _ = w.Write(source[pos:stop])
+			return
+		}
+		_, _ = w.Write(source[pos : pos+i+1])
+		r.context.Pad(w)
+		pos += i + 1
+
+		if padded {
+			// skip the source padding up to the content of the line, the line is blank without it
+			lineEnd := stop
+			if i := bytes.IndexByte(source[pos:stop], '\n'); i >= 0 {
+				lineEnd = pos + i
+			}
+			next := lineEnd
+			if j, _ := slices.BinarySearch(starts, pos); j < len(starts) && starts[j] < lineEnd {
+				next = starts[j]
+			}
+			pos = next
+		}
+	}
+}
@@ -2,12 +2,15 @@ package markdown
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"io"
 	"slices"
 	"strings"
 	"sync"
 
 	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
 	goldrender "github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/util"
 )
@@ -22,6 +25,9 @@ type Renderer struct {
 	initSync sync.Once
 
 	context *Context
+
+	// err fails every render, see MD2MD
+	err error
 }
 
 func NewRenderer(context *Context, ps ...util.PrioritizedValue) goldrender.Renderer {
@@ -30,12 +36,28 @@ func NewRenderer(context *Context, ps ...util.PrioritizedValue) goldrender.Rende
 		nodeRendererFuncsTmp: map[ast.NodeKind]NodeRendererFunc{},
 		context:              context,
 	}
+	context.render = r.renderNode
 
 	return r
 }
 
-func (r *Renderer) AddOptions(_ ...goldrender.Option) {
-	// Nothing to add
+// AddOptions implements goldmark renderer.Renderer, it takes markdown NodeRenderers
+// from renderer.WithNodeRenderers(); others, like html ones of goldmark extensions, are ignored.
+func (r *Renderer) AddOptions(opts ...goldrender.Option) {
+	config := goldrender.NewConfig()
+	for _, opt := range opts {
+		opt.SetConfig(config)
+	}
+	for _, v := range config.NodeRenderers {
+		if _, ok := v.Value.(NodeRenderer); ok {
+			r.NodeRenderers = append(r.NodeRenderers, v)
+		}
+	}
+}
+
+// Context returns the Context shared by node renderers.
+func (r *Renderer) Context() *Context {
+	return r.context
 }
 
 func (r *Renderer) Register(kind ast.NodeKind, v NodeRendererFunc) {
@@ -45,7 +67,12 @@ func (r *Renderer) Register(kind ast.NodeKind, v NodeRendererFunc) {
 	}
 }
 
+// Render implements goldmark renderer.Renderer. Nothing is written to w if it returns an error,
+// e.g. UnsupportedNodesError or RenderError.
 func (r *Renderer) Render(w io.Writer, source []byte, root ast.Node) error {
+	if r.err != nil {
+		return r.err
+	}
 	r.initSync.Do(func() {
 		r.NodeRenderers.Sort()
 		l := len(r.NodeRenderers)
@@ -60,11 +87,54 @@ func (r *Renderer) Render(w io.Writer, source []byte, root ast.Node) error {
 		}
 		r.nodeRendererFuncsTmp = nil
 	})
-	writer, ok := w.(util.BufWriter)
-	if !ok {
-		writer = bufio.NewWriter(w)
+	// the output is buffered, so nothing reaches w from a failed render
+	var out bytes.Buffer
+	writer := bufio.NewWriter(&out)
+	// every call has its own state, so concurrent and failed renders don't affect each other
+	state := &renderState{}
+	rw := newRenderWriter(writer, state)
+	if r.context.Config.KeepSource {
+		state.changed, state.spliced = changedBlocks(source, root)
+		r.writeSourceBefore(rw, source, root)
+	}
+	if err := r.renderNode(rw, source, root); err != nil {
+		return err
+	}
+	if r.context.Config.KeepSource {
+		r.writeSourceAfter(rw, source, root)
+	}
+	if len(state.unsupported) > 0 {
+		return &UnsupportedNodesError{Nodes: state.unsupported}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.Write(out.Bytes()); err != nil {
+		return err
+	}
+	if bw, ok := w.(util.BufWriter); ok {
+		return bw.Flush()
 	}
-	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return nil
+}
+
+// unsupportedNode handles a node without NodeRendererFunc according to Config.UnsupportedNodes
+func (r *Renderer) unsupportedNode(w util.BufWriter, source []byte, n ast.Node) {
+	switch r.context.Config.UnsupportedNodes {
+	case UnsupportedNodesWarn:
+		if warn := r.context.Config.Warn; warn != nil {
+			warn(newRenderError(source, n, ErrUnsupportedNode))
+		}
+	case UnsupportedNodesStrict:
+		state := r.context.state(w)
+		state.unsupported = append(state.unsupported, newRenderError(source, n, ErrUnsupportedNode))
+	}
+}
+
+// renderNode walks the subtree of n, so node renderers can render a part of the AST
+// into their own writer via Context.RenderChildren
+func (r *Renderer) renderNode(writer util.BufWriter, source []byte, root ast.Node) error {
+	return ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		s := ast.WalkStatus(ast.WalkContinue)
 
 		var f NodeRendererFunc
@@ -72,34 +142,68 @@ func (r *Renderer) Render(w io.Writer, source []byte, root ast.Node) error {
 			f = r.nodeRendererFuncs[k]
 		}
 
-		if f != nil {
+		copied := r.keepsSource(writer, n)
+		if copied {
+			if entering {
+				r.writeSource(writer, source, n)
+			}
+			s = ast.WalkSkipChildren
+		} else if r.splicesSource(writer, n) && n.Kind() != ast.KindDocument && n.Kind() != east.KindTable {
+			r.writeSourcePrefix(writer, source, n, entering)
+		} else if f != nil {
 			sF, err := f(writer, source, n, entering)
 			if err != nil {
+				// the innermost node is reported when children are rendered via Context.RenderChildren
+				var renderErr *RenderError
+				if !errors.As(err, &renderErr) {
+					err = newRenderError(source, n, err)
+				}
 				return sF, err
 			}
 
 			s = sF
+		} else if entering {
+			r.unsupportedNode(writer, source, n)
+			if start, stop := rawRange(source, n); start >= 0 {
+				r.writeRaw(writer, source, n, start, stop)
+				s = ast.WalkSkipChildren
+			}
 		}
 
 		if !entering && n.Type() == ast.TypeBlock && n.NextSibling() != nil {
 			sep := "\n\n"
 
 			kind := n.Kind()
+			lineEnding := slices.Contains(lineEndingKinds, kind)
+			// blank lines between blocks of a spliced container are from the source too
+			if copied || !lineEnding && r.splicesSource(writer, n.Parent()) {
+				if r.writeSourceGap(writer, source, n, n.NextSibling()) {
+					return s, nil
+				}
+			}
+			if copied && lineEnding {
+				// as their node renderers do
+				_ = writer.WriteByte('\n')
+			}
 			if kind == ast.KindListItem && n.Parent().(*ast.List).IsTight {
 				sep = "\n"
+			} else if kind == east.KindDefinitionTerm || kind == east.KindDefinitionDescription {
+				sep = definitionListItemSeparator(n)
+			} else if kind == KindFrontMatter {
+				sep = frontMatterSeparator(n)
 			} else if list, ok := n.NextSibling().(*ast.List); ok && (!list.IsOrdered() || list.Start == 1) && !list.HasBlankPreviousLines() {
 				// In CommonMark, we do allow lists to interrupt paragraphss
 				sep = "\n"
-			} else if slices.Contains([]ast.NodeKind{ast.KindHTMLBlock, ast.KindCodeBlock, ast.KindThematicBreak}, kind) {
+			} else if lineEnding {
 				// htmlBlockParser puts lines and ClosureLine with "\n", so we need to add just one
-				if pad := barePaddingLine(r.context); pad != "" {
+				if pad := barePaddingLine(r.context, writer); pad != "" {
 					_, _ = writer.WriteString(pad)
 				}
 				sep = "\n"
 			}
 
 			if sep == "\n\n" {
-				if pad := barePaddingLine(r.context); pad != "" {
+				if pad := barePaddingLine(r.context, writer); pad != "" {
 					_, _ = writer.WriteString("\n" + pad)
 					sep = "\n"
 				}
@@ -111,13 +215,12 @@ func (r *Renderer) Render(w io.Writer, source []byte, root ast.Node) error {
 
 		return s, nil
 	})
-	if err != nil {
-		return err
-	}
-	return writer.Flush()
 }
 
-func barePaddingLine(context *Context) string {
+// lineEndingKinds are blocks whose node renderers end them with a newline
+var lineEndingKinds = []ast.NodeKind{ast.KindHTMLBlock, ast.KindCodeBlock, ast.KindThematicBreak}
+
+func barePaddingLine(context *Context, w util.BufWriter) string {
 	// if padding stack has a blockquote then we have to pad it after every newline
-	return strings.TrimRight(strings.Join(context.PaddingStack, ""), " \t\n\x0b\x0c\x0d")
+	return strings.TrimRight(context.padding(w), " \t\n\x0b\x0c\x0d")
 }
@@ -0,0 +1,257 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestRendererConcurrent(t *testing.T) {
+	for _, format := range []Format{FormatMarkdown, FormatText} {
+		md := newMarkdown(NewOptions(WithFormat(format)))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				src := fmt.Sprintf("> - item %d\n>\n>   para\n>\n> > nested\n\n1. one\n\n   | a |\n   | --- |\n   | %d |", i, i)
+				expected := src
+				if format == FormatText {
+					expected = fmt.Sprintf("item %d\n\npara\n\nnested\n\none\n\na\n%d", i, i)
+				}
+				for j := 0; j < 10; j++ {
+					var buf bytes.Buffer
+					assert.NoError(t, md.Convert([]byte(src), &buf))
+					assert.Equal(t, expected, buf.String())
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+var errFailingRenderer = errors.New("failing renderer")
+
+type failingRenderer struct {
+}
+
+func (r *failingRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindCodeSpan, func(util.BufWriter, []byte, ast.Node, bool) (ast.WalkStatus, error) {
+		return ast.WalkStop, errFailingRenderer
+	})
+}
+
+func TestRendererAfterError(t *testing.T) {
+	md := newMarkdown(NewOptions(WithNodeRenderer(&failingRenderer{}, 0)))
+
+	var buf bytes.Buffer
+	err := md.Convert([]byte("para\n\n> - `code`"), &buf)
+	assert.ErrorIs(t, err, errFailingRenderer)
+	assert.Empty(t, buf.String(), "nothing is written on error")
+
+	// no padding is left from the failed render
+	buf.Reset()
+	assert.NoError(t, md.Convert([]byte("a\nb"), &buf))
+	assert.Equal(t, "a\nb", buf.String())
+}
+
+func TestRenderError(t *testing.T) {
+	err := Convert([]byte("para\n\n> - `code`"), io.Discard, WithNodeRenderer(&failingRenderer{}, 0))
+
+	var renderErr *RenderError
+	if !assert.ErrorAs(t, err, &renderErr) {
+		return
+	}
+	assert.Equal(t, ast.KindCodeSpan, renderErr.Kind)
+	assert.Equal(t, 3, renderErr.Line)
+	assert.Equal(t, 5, renderErr.Column)
+	assert.ErrorIs(t, err, errFailingRenderer)
+	assert.Equal(t, "3:5: CodeSpan: failing renderer", err.Error())
+}
+
+func TestRenderErrorListItem(t *testing.T) {
+	source := []byte("item")
+	doc := ast.NewDocument()
+	item := ast.NewListItem(2)
+	doc.AppendChild(doc, item)
+	block := ast.NewTextBlock()
+	block.Lines().Append(text.NewSegment(0, len(source)))
+	item.AppendChild(item, block)
+
+	md := newMarkdown(NewOptions())
+	err := md.Renderer().Render(io.Discard, source, doc)
+
+	var renderErr *RenderError
+	if !assert.ErrorAs(t, err, &renderErr) {
+		return
+	}
+	assert.Equal(t, ast.KindListItem, renderErr.Kind)
+	assert.Equal(t, "1:1: ListItem: expected list node but got: Document", err.Error())
+}
+
+var kindUnknown = ast.NewNodeKind("Unknown")
+
+type unknownNode struct {
+	ast.BaseInline
+}
+
+func (n *unknownNode) Kind() ast.NodeKind {
+	return kindUnknown
+}
+
+func (n *unknownNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// parseWithUnknown parses source and wraps the first inline of paragraphs into unknownNode
+func parseWithUnknown(md goldmark.Markdown, source []byte) ast.Node {
+	doc := md.Parser().Parse(text.NewReader(source))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == ast.KindParagraph {
+			t := n.FirstChild()
+			unknown := &unknownNode{}
+			n.ReplaceChild(n, t, unknown)
+			unknown.AppendChild(unknown, t)
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc
+}
+
+func TestUnsupportedNodes(t *testing.T) {
+	source := []byte("a\n\n> b")
+	for _, mode := range []UnsupportedNodes{UnsupportedNodesIgnore, UnsupportedNodesWarn} {
+		var warnings []string
+		md := newMarkdown(NewOptions(WithMarkdownOptions(
+			WithUnsupportedNodes(mode),
+			WithWarn(func(err error) {
+				assert.ErrorIs(t, err, ErrUnsupportedNode)
+				warnings = append(warnings, err.Error())
+			}),
+		)))
+
+		var buf bytes.Buffer
+		assert.NoError(t, md.Renderer().Render(&buf, source, parseWithUnknown(md, source)))
+		assert.Equal(t, "a\n\n> b", buf.String())
+		if mode == UnsupportedNodesWarn {
+			assert.Len(t, warnings, 2)
+		} else {
+			assert.Empty(t, warnings)
+		}
+	}
+
+	md := newMarkdown(NewOptions(WithMarkdownOptions(WithUnsupportedNodes(UnsupportedNodesStrict))))
+	var buf bytes.Buffer
+	err := md.Renderer().Render(&buf, source, parseWithUnknown(md, source))
+	assert.Empty(t, buf.String(), "nothing is written on error")
+
+	var unsupportedErr *UnsupportedNodesError
+	if !assert.ErrorAs(t, err, &unsupportedErr) {
+		return
+	}
+	assert.ErrorIs(t, err, ErrUnsupportedNode)
+	assert.Equal(t, "unsupported node kinds: Unknown at line 1, Unknown at line 3", err.Error())
+	if assert.Len(t, unsupportedErr.Nodes, 2) {
+		assert.Equal(t, kindUnknown, unsupportedErr.Nodes[1].Kind)
+		assert.Equal(t, 3, unsupportedErr.Nodes[1].Line)
+		assert.Equal(t, 3, unsupportedErr.Nodes[1].Column)
+	}
+}
+
+// markParser parses ==marked== text into unknownNode, like extension.Strikethrough does
+type markParser struct {
+}
+
+func (p *markParser) Trigger() []byte {
+	return []byte{'='}
+}
+
+func (p *markParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 2, &markDelimiterProcessor{})
+	if node == nil || node.OriginalLength != 2 {
+		return nil
+	}
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+type markDelimiterProcessor struct {
+}
+
+func (p *markDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == '='
+}
+
+func (p *markDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
+
+func (p *markDelimiterProcessor) OnMatch(consumes int) ast.Node {
+	return &unknownNode{}
+}
+
+type unknownBlock struct {
+	ast.BaseBlock
+}
+
+func (n *unknownBlock) Kind() ast.NodeKind {
+	return kindUnknown
+}
+
+func (n *unknownBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+func TestRawFallback(t *testing.T) {
+	markOption := WithParserOptions(parser.WithInlineParsers(util.Prioritized(&markParser{}, 500)))
+	for _, source := range []string{
+		"a ==b *c*== d",
+		"==a==",
+		"> x ==y\n> z== w",
+		"> x\n> ==y== z",
+		"- a\n\n  ==b\n  c==\n\n- d",
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, Convert([]byte(source), &buf, markOption))
+		assert.Equal(t, source, buf.String())
+	}
+
+	// blocks of unknown syntax keep their lines
+	for _, source := range []string{
+		"::: note\n  text\n:::\n\npara",
+		"- ::: note\n  text\n\n  more",
+		"> a\n>\n> ::: note\n> text",
+	} {
+		md := newMarkdown(NewOptions())
+		doc := md.Parser().Parse(text.NewReader([]byte(source)))
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if entering && n.Kind() == ast.KindParagraph && bytes.HasPrefix(n.Lines().Value([]byte(source)), []byte(":::")) {
+				block := &unknownBlock{}
+				block.SetLines(n.Lines())
+				n.Parent().ReplaceChild(n.Parent(), n, block)
+				return ast.WalkSkipChildren, nil
+			}
+			return ast.WalkContinue, nil
+		})
+
+		var buf bytes.Buffer
+		assert.NoError(t, md.Renderer().Render(&buf, []byte(source), doc))
+		assert.Equal(t, source, buf.String())
+	}
+}
@@ -0,0 +1,498 @@
+package markdown
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Segment is a translation unit of a document: the text of a heading, a paragraph, a table cell
+// or a definition term, the alt text of an image or the title of a link, taken from the link
+// reference definition for reference links. Inline markup becomes numbered placeholders in Text:
+// {1}emphasis{/1} for markup around text and {2/} for the one standing alone, like code spans;
+// literal "{" is written as "{{". Soft line breaks are kept as "\n".
+type Segment struct {
+	// ID is made of the kind and the text, so it doesn't change with other segments;
+	// repeated segments get -2, -3 and so on
+	ID string
+
+	// Kind is the kind of the block, or "ImageAlt" and "LinkTitle"
+	Kind string
+
+	Text         string
+	Placeholders []Placeholder
+
+	// Line is the 1-based source line, 0 if unknown
+	Line int
+}
+
+// Placeholder stands for inline markup in Segment.Text.
+type Placeholder struct {
+	ID int
+
+	// Paired is markup around text, like emphasis or links
+	Paired bool
+
+	// Kind is the kind of the inline node, "HardLineBreak" for hard line breaks
+	Kind string
+
+	// Start and End are the markup as in markdown, End is for paired ones only
+	Start string
+	End   string
+}
+
+const (
+	segmentKindImageAlt  = "ImageAlt"
+	segmentKindLinkTitle = "LinkTitle"
+	placeholderLineBreak = "HardLineBreak"
+)
+
+// translationUnit is a Segment with nodes to put the translation into
+type translationUnit struct {
+	Segment
+
+	node ast.Node
+
+	// nodes of placeholders by their IDs
+	nodes map[int]ast.Node
+
+	// the title of a LinkTitle unit of link reference definitions, see linkDefinitionTitles
+	definition int
+}
+
+// ExtractSegments returns translation units of doc in the document order.
+func ExtractSegments(doc ast.Node, source []byte) []Segment {
+	var segments []Segment
+	for _, u := range translationUnits(doc, source) {
+		segments = append(segments, u.Segment)
+	}
+	return segments
+}
+
+// ApplySegments puts translations of segments by their IDs into doc, the segments without
+// a translation are kept. Translations must have all placeholders of their segments.
+func ApplySegments(doc ast.Node, source []byte, translations map[string]string) error {
+	for _, u := range translationUnits(doc, source) {
+		translation, ok := translations[u.ID]
+		if !ok || translation == u.Text {
+			continue
+		}
+		if err := u.apply(source, translation); err != nil {
+			return fmt.Errorf("segment %s: %w", u.ID, err)
+		}
+	}
+	return nil
+}
+
+// Segments parses source as Convert does and returns its translation units.
+func Segments(source []byte, opts ...ConvertOption) []Segment {
+	md := newMarkdown(NewOptions(opts...))
+	return ExtractSegments(md.Parser().Parse(text.NewReader(source)), source)
+}
+
+// Translate renders source to markdown with translations of its segments, see Segments.
+func Translate(source []byte, writer io.Writer, translations map[string]string, opts ...ConvertOption) error {
+	md := newMarkdown(NewOptions(append([]ConvertOption{WithFormat(FormatMarkdown)}, opts...)...))
+	doc := md.Parser().Parse(text.NewReader(source))
+	if err := ApplySegments(doc, source, translations); err != nil {
+		return err
+	}
+	return md.Renderer().Render(writer, source, doc)
+}
+
+func isSegmentBlock(n ast.Node) bool {
+	switch n.Kind() {
+	case ast.KindHeading, ast.KindParagraph, ast.KindTextBlock, east.KindTableCell, east.KindDefinitionTerm:
+		return true
+	}
+	return false
+}
+
+func translationUnits(doc ast.Node, source []byte) []*translationUnit {
+	var units []*translationUnit
+	seen := map[string]int{}
+	add := func(u *translationUnit) {
+		if strings.TrimSpace(placeholderRegexp.ReplaceAllString(u.Text, "")) == "" {
+			return
+		}
+		sum := sha1.Sum([]byte(u.Kind + "\x00" + u.Text))
+		u.ID = hex.EncodeToString(sum[:6])
+		if seen[u.ID]++; seen[u.ID] > 1 {
+			u.ID += "-" + strconv.Itoa(seen[u.ID])
+		}
+		if offset := nodeOffset(u.node); offset >= 0 {
+			u.Line, _ = position(source, offset)
+		}
+		units = append(units, u)
+	}
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if defs, ok := n.(*LinkReferenceDefinitions); entering && ok {
+			raw := defs.Text(source)
+			for i, title := range linkDefinitionTitles(raw) {
+				add(&translationUnit{
+					Segment:    Segment{Kind: segmentKindLinkTitle, Text: escapeSegmentText(string(raw[title[0]:title[1]]))},
+					node:       defs,
+					definition: i,
+				})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		if !entering || !isSegmentBlock(n) {
+			return ast.WalkContinue, nil
+		}
+
+		u := &translationUnit{
+			Segment: Segment{Kind: n.Kind().String()},
+			node:    n,
+			nodes:   map[int]ast.Node{},
+		}
+		var b strings.Builder
+		u.writeInlines(&b, source, n)
+		u.Text = b.String()
+		add(u)
+
+		// alt texts and titles are units of their own; titles of reference links are taken from
+		// their definitions, so that they keep referring to them
+		_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			_, isReference := attribute(c, linkReferenceAttr)
+			switch c := c.(type) {
+			case *ast.Image:
+				add(&translationUnit{
+					Segment: Segment{Kind: segmentKindImageAlt, Text: escapeSegmentText(string(plainText(c, source)))},
+					node:    c,
+				})
+				if len(c.Title) > 0 && !isReference {
+					add(&translationUnit{Segment: Segment{Kind: segmentKindLinkTitle, Text: escapeSegmentText(string(c.Title))}, node: c})
+				}
+				return ast.WalkSkipChildren, nil
+			case *ast.Link:
+				if len(c.Title) > 0 && !isReference {
+					add(&translationUnit{Segment: Segment{Kind: segmentKindLinkTitle, Text: escapeSegmentText(string(c.Title))}, node: c})
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return units
+}
+
+func escapeSegmentText(s string) string {
+	return strings.ReplaceAll(s, "{", "{{")
+}
+
+// textValue returns the text of t as it is displayed
+func textValue(t *ast.Text, source []byte) []byte {
+	b := t.Segment.Value(source)
+	if !t.IsRaw() {
+		b = util.UnescapePunctuations(b)
+		b = util.ResolveNumericReferences(b)
+		b = util.ResolveEntityNames(b)
+	}
+	return b
+}
+
+// plainText returns the text of inlines of n without markup
+func plainText(n ast.Node, source []byte) []byte {
+	var b []byte
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			b = append(b, textValue(c, source)...)
+			if c.SoftLineBreak() || c.HardLineBreak() {
+				b = append(b, ' ')
+			}
+		case *ast.String:
+			b = append(b, c.Value...)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b
+}
+
+func isPairedInline(n ast.Node) bool {
+	switch n.Kind() {
+	case ast.KindEmphasis, ast.KindLink, east.KindStrikethrough:
+		return true
+	}
+	return false
+}
+
+func (u *translationUnit) placeholder(n ast.Node, kind string, paired bool, start, end string) int {
+	id := len(u.Placeholders) + 1
+	u.Placeholders = append(u.Placeholders, Placeholder{ID: id, Paired: paired, Kind: kind, Start: start, End: end})
+	u.nodes[id] = n
+	return id
+}
+
+// writeInlines writes the text of inline children of n with placeholders
+func (u *translationUnit) writeInlines(b *strings.Builder, source []byte, n ast.Node) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			b.WriteString(escapeSegmentText(string(textValue(c, source))))
+			if c.HardLineBreak() {
+				// an empty text is put back for it
+				lineBreak := ast.NewTextSegment(text.NewSegment(c.Segment.Stop, c.Segment.Stop))
+				lineBreak.SetHardLineBreak(true)
+				fmt.Fprintf(b, "{%d/}", u.placeholder(lineBreak, placeholderLineBreak, false, "\\\n", ""))
+			} else if c.SoftLineBreak() {
+				// apply puts it back from the translation
+				b.WriteByte('\n')
+			}
+		case *ast.String:
+			b.WriteString(escapeSegmentText(string(c.Value)))
+		default:
+			start, end := inlineMarkup(c, source)
+			if isPairedInline(c) {
+				id := u.placeholder(c, c.Kind().String(), true, start, end)
+				fmt.Fprintf(b, "{%d}", id)
+				u.writeInlines(b, source, c)
+				fmt.Fprintf(b, "{/%d}", id)
+			} else {
+				fmt.Fprintf(b, "{%d/}", u.placeholder(c, c.Kind().String(), false, start, ""))
+			}
+		}
+	}
+}
+
+// inlineMarkup returns the markdown of n for placeholders, the text of paired ones goes between
+// start and end
+func inlineMarkup(n ast.Node, source []byte) (string, string) {
+	switch n := n.(type) {
+	case *ast.Emphasis:
+		marker := strings.Repeat(string(emphasisMarker(n)), n.Level)
+		return marker, marker
+	case *east.Strikethrough:
+		return "~~", "~~"
+	case *ast.Link:
+		return "[", linkEnd(n, string(plainText(n, source)), n.Destination, n.Title)
+	case *ast.Image:
+		alt := string(plainText(n, source))
+		return "![" + alt + linkEnd(n, alt, n.Destination, n.Title), ""
+	case *ast.AutoLink:
+		return "<" + string(n.URL(source)) + ">", ""
+	case *ast.CodeSpan:
+		return codeSpanMarkdown(source, n, string(plainText(n, source))), ""
+	case *ast.RawHTML:
+		var b []byte
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			b = append(b, segment.Value(source)...)
+		}
+		return string(b), ""
+	case *east.FootnoteLink:
+		return "[^" + string(footnoteRef(n)) + "]", ""
+	case *east.TaskCheckBox:
+		if n.IsChecked {
+			return "[x]", ""
+		}
+		return "[ ]", ""
+	}
+	return n.Kind().String(), ""
+}
+
+// placeholderRegexp matches {1}, {1/} and {/1} but not {{
+var placeholderRegexp = regexp.MustCompile(`\{\{|\{(\d+)(/?)\}|\{/(\d+)\}`)
+
+// TokenKind is the kind of SegmentToken.
+type TokenKind int
+
+const (
+	// TokenText is text
+	TokenText TokenKind = iota
+
+	// TokenOpen is {1}
+	TokenOpen
+
+	// TokenClose is {/1}
+	TokenClose
+
+	// TokenStandalone is {1/}
+	TokenStandalone
+)
+
+// SegmentToken is text or a placeholder of Segment.Text.
+type SegmentToken struct {
+	Kind TokenKind
+
+	// Text is the text of TokenText, "{{" unescaped
+	Text string
+
+	// ID is the placeholder ID
+	ID int
+}
+
+// String returns t as in Segment.Text.
+func (t SegmentToken) String() string {
+	switch t.Kind {
+	case TokenOpen:
+		return fmt.Sprintf("{%d}", t.ID)
+	case TokenClose:
+		return fmt.Sprintf("{/%d}", t.ID)
+	case TokenStandalone:
+		return fmt.Sprintf("{%d/}", t.ID)
+	}
+	return escapeSegmentText(t.Text)
+}
+
+// SegmentTokens splits the text of a segment into text and placeholders.
+func SegmentTokens(s string) []SegmentToken {
+	var tokens []SegmentToken
+	var b strings.Builder
+	last := 0
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+
+		t := SegmentToken{Kind: TokenOpen}
+		switch {
+		case m[2] < 0 && m[6] < 0:
+			b.WriteByte('{')
+			continue
+		case m[6] >= 0:
+			t.Kind = TokenClose
+			t.ID, _ = strconv.Atoi(s[m[6]:m[7]])
+		default:
+			if m[5] > m[4] {
+				t.Kind = TokenStandalone
+			}
+			t.ID, _ = strconv.Atoi(s[m[2]:m[3]])
+		}
+		if b.Len() > 0 {
+			tokens = append(tokens, SegmentToken{Kind: TokenText, Text: b.String()})
+			b.Reset()
+		}
+		tokens = append(tokens, t)
+	}
+	b.WriteString(s[last:])
+	if b.Len() > 0 {
+		tokens = append(tokens, SegmentToken{Kind: TokenText, Text: b.String()})
+	}
+	return tokens
+}
+
+func (u *translationUnit) apply(source []byte, translation string) error {
+	switch u.Kind {
+	case segmentKindImageAlt:
+		alt, err := segmentPlainText(translation)
+		if err != nil {
+			return err
+		}
+		u.node.RemoveChildren(u.node)
+		u.node.AppendChild(u.node, ast.NewString([]byte(alt)))
+		return nil
+	case segmentKindLinkTitle:
+		title, err := segmentPlainText(translation)
+		if err != nil {
+			return err
+		}
+		switch n := u.node.(type) {
+		case *ast.Link:
+			n.Title = []byte(title)
+		case *ast.Image:
+			n.Title = []byte(title)
+		case *LinkReferenceDefinitions:
+			return n.setTitle(source, u.definition, []byte(title))
+		}
+		return nil
+	}
+
+	for _, n := range u.nodes {
+		if isPairedInline(n) {
+			n.RemoveChildren(n)
+		}
+	}
+	u.node.RemoveChildren(u.node)
+
+	stack := []ast.Node{u.node}
+	used := map[int]bool{}
+	appendText := func(s string) {
+		parent := stack[len(stack)-1]
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				// a soft line break, on an empty text as String has no line breaks
+				lineBreak := ast.NewText()
+				lineBreak.SetSoftLineBreak(true)
+				parent.AppendChild(parent, lineBreak)
+			}
+			if line != "" {
+				parent.AppendChild(parent, ast.NewString([]byte(line)))
+			}
+		}
+	}
+
+	for _, t := range SegmentTokens(translation) {
+		if t.Kind == TokenText {
+			appendText(t.Text)
+			continue
+		}
+
+		n, ok := u.nodes[t.ID]
+		switch {
+		case !ok:
+			return fmt.Errorf("unknown placeholder %s", t)
+		case t.Kind == TokenClose:
+			if top := stack[len(stack)-1]; top != n {
+				return fmt.Errorf("unexpected %s", t)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		case used[t.ID]:
+			return fmt.Errorf("repeated placeholder %s", t)
+		case (t.Kind == TokenStandalone) == isPairedInline(n):
+			return fmt.Errorf("placeholder %s must be %s", t, placeholderText(u.Placeholders[t.ID-1]))
+		}
+		used[t.ID] = true
+		parent := stack[len(stack)-1]
+		parent.AppendChild(parent, n)
+		if t.Kind == TokenOpen {
+			stack = append(stack, n)
+		}
+	}
+
+	for _, p := range u.Placeholders {
+		if len(stack) > 1 && u.nodes[p.ID] == stack[len(stack)-1] {
+			return fmt.Errorf("unclosed placeholder {%d}", p.ID)
+		}
+		if !used[p.ID] {
+			return fmt.Errorf("missing placeholder %s", placeholderText(p))
+		}
+	}
+	return nil
+}
+
+func placeholderText(p Placeholder) string {
+	if p.Paired {
+		return fmt.Sprintf("{%d}...{/%d}", p.ID, p.ID)
+	}
+	return fmt.Sprintf("{%d/}", p.ID)
+}
+
+// segmentPlainText returns the text of a translation without placeholders, for alt texts and titles
+func segmentPlainText(translation string) (string, error) {
+	var b strings.Builder
+	for _, t := range SegmentTokens(translation) {
+		if t.Kind != TokenText {
+			return "", fmt.Errorf("unknown placeholder %s", t)
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String(), nil
+}
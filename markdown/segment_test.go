@@ -0,0 +1,142 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func segmentTexts(segments []Segment) []string {
+	var texts []string
+	for _, s := range segments {
+		texts = append(texts, s.Kind+": "+s.Text)
+	}
+	return texts
+}
+
+func TestSegments(t *testing.T) {
+	src := "# Hello *world*\n\n" +
+		"Text with `code`, [a link](/a \"Title\") and ![alt *text*](/b.png)\\\nnext {line}.\n\n" +
+		"- item **one**\n- ![](/c.png)\n\n" +
+		"| a | b |\n| --- | --- |\n| ~~c~~ | <b>d</b> |\n\n" +
+		"```\ncode block\n```\n\n" +
+		"- item **one**"
+
+	segments := Segments([]byte(src))
+	assert.Equal(t, []string{
+		"Heading: Hello {1}world{/1}",
+		"Paragraph: Text with {1/}, {2}a link{/2} and {3/}{4/}next {{line}.",
+		"LinkTitle: Title",
+		"ImageAlt: alt text",
+		"TextBlock: item {1}one{/1}",
+		"TableCell: a",
+		"TableCell: b",
+		"TableCell: {1}c{/1}",
+		"TableCell: {1/}d{2/}",
+		"TextBlock: item {1}one{/1}",
+	}, segmentTexts(segments))
+
+	if assert.Len(t, segments, 10) {
+		assert.Equal(t, []Placeholder{
+			{ID: 1, Kind: "CodeSpan", Start: "`code`"},
+			{ID: 2, Paired: true, Kind: "Link", Start: "[", End: "](/a \"Title\")"},
+			{ID: 3, Kind: "Image", Start: "![alt text](/b.png)"},
+			{ID: 4, Kind: "HardLineBreak", Start: "\\\n"},
+		}, segments[1].Placeholders)
+		assert.Equal(t, 3, segments[1].Line)
+		assert.Equal(t, segments[4].ID+"-2", segments[9].ID)
+	}
+
+	// IDs don't depend on other segments
+	assert.Equal(t, segments[4].ID, Segments([]byte("Intro\n\n" + src))[5].ID)
+}
+
+func TestSegmentPlaceholders(t *testing.T) {
+	// placeholders have the markup of the source
+	segments := Segments([]byte("[a][ref], [b] and ``c`d``.\n\n[ref]: /a 'T'\n[b]: /b\n"))
+	if assert.Len(t, segments, 2) {
+		assert.Equal(t, []Placeholder{
+			{ID: 1, Paired: true, Kind: "Link", Start: "[", End: "][ref]"},
+			{ID: 2, Paired: true, Kind: "Link", Start: "[", End: "]"},
+			{ID: 3, Kind: "CodeSpan", Start: "``c`d``"},
+		}, segments[0].Placeholders)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	src := "# Hello *world*\n\n" +
+		"Text with `code`, [a link](/a \"Title\") and ![alt](/b.png)\\\nnext line.\n\n" +
+		"```\ncode block\n```\n\n" +
+		"- item **one**\n- item two\n"
+
+	translations := map[string]string{}
+	for _, s := range Segments([]byte(src)) {
+		translations[s.ID] = map[string]string{
+			"Hello {1}world{/1}": "{1}Привет{/1}, мир",
+			"Text with {1/}, {2}a link{/2} and {3/}{4/}next line.": "Текст с {1/}, {2}ссылкой{/2} и {3/}{4/}* не список {{}.",
+			"Title":           "Заголовок",
+			"alt":             "картинка",
+			"item {1}one{/1}": "пункт {1}один{/1}",
+		}[s.Text]
+		if translations[s.ID] == "" {
+			delete(translations, s.ID)
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Translate([]byte(src), &buf, translations, WithMarkdownOptions(WithKeepSource(true))))
+	assert.Equal(t, "# *Привет*, мир\n\n"+
+		"Текст с `code`, [ссылкой](/a \"Заголовок\") и ![картинка](/b.png)\\\n\\* не список {}.\n\n"+
+		"```\ncode block\n```\n\n"+
+		"- пункт **один**\n- item two\n", buf.String())
+
+	// translated text is parsed back the same
+	assert.Equal(t, []string{
+		"Heading: {1}Привет{/1}, мир",
+		"Paragraph: Текст с {1/}, {2}ссылкой{/2} и {3/}{4/}* не список {{}.",
+		"LinkTitle: Заголовок",
+		"ImageAlt: картинка",
+		"TextBlock: пункт {1}один{/1}",
+		"TextBlock: item two",
+	}, segmentTexts(Segments(buf.Bytes())))
+}
+
+func TestTranslateErrors(t *testing.T) {
+	src := []byte("Text *with* `code`.")
+	id := Segments(src)[0].ID
+	for translation, message := range map[string]string{
+		"Текст {1}с{/1}.":           "missing placeholder {2/}",
+		"Текст {1}с{/1} {2/} {2/}.": "repeated placeholder {2/}",
+		"Текст {1}с {2/}.":          "unclosed placeholder {1}",
+		"Текст {1}с{/2} {2/}.":      "unexpected {/2}",
+		"Текст {1/}с {2/}.":         "placeholder {1/} must be {1}...{/1}",
+		"Текст {1}с{/1} {2/} {3/}.": "unknown placeholder {3/}",
+	} {
+		err := Translate(src, &bytes.Buffer{}, map[string]string{id: translation})
+		assert.EqualError(t, err, "segment "+id+": "+message, translation)
+	}
+}
+
+func TestTranslateReferenceLinks(t *testing.T) {
+	src := "A [link][ref] and\n[another][ref].\n\n[ref]: /a\n  \"Title\"\n[other]: /b\n"
+
+	// the title is taken once from the definition, soft line breaks are kept
+	segments := Segments([]byte(src))
+	assert.Equal(t, []string{
+		"Paragraph: A {1}link{/1} and\n{2}another{/2}.",
+		"LinkTitle: Title",
+	}, segmentTexts(segments))
+
+	translations := map[string]string{}
+	for _, s := range segments {
+		translations[s.ID] = map[string]string{
+			"A {1}link{/1} and\n{2}another{/2}.": "Б {1}ссылка{/1} и\n{2}другая{/2}.",
+			"Title":                              `Заголовок "с" кавычками`,
+		}[s.Text]
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Translate([]byte(src), &buf, translations, WithMarkdownOptions(WithKeepSource(true))))
+	assert.Equal(t, "Б [ссылка][ref] и\n[другая][ref].\n\n[ref]: /a\n  'Заголовок \"с\" кавычками'\n[other]: /b\n", buf.String())
+}
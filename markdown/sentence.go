@@ -0,0 +1,258 @@
+package markdown
+
+import (
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Segments are split into sentences for translation memories by rules like SRX 2.0 ones:
+// a language rule is a list of rules, the first one matching at a position tells if there is
+// a break; a map rule tells which language rules a language goes with.
+
+//go:embed sentence.srx
+var defaultSentenceRules []byte
+
+// SentenceRules split text into sentences.
+type SentenceRules struct {
+	rules   map[string][]sentenceRule
+	maps    []languageMap
+	cascade bool
+}
+
+type sentenceRule struct {
+	isBreak bool
+
+	// find finds candidates for breaks, before and after are matched at a candidate
+	find, before, after *regexp.Regexp
+}
+
+type languageMap struct {
+	pattern *regexp.Regexp
+	rules   string
+}
+
+// srxDocument is the part of SRX 2.0 we read
+type srxDocument struct {
+	Header struct {
+		Cascade string `xml:"cascade,attr"`
+	} `xml:"header"`
+	LanguageRules []struct {
+		Name  string `xml:"languagerulename,attr"`
+		Rules []struct {
+			Break  string `xml:"break,attr"`
+			Before string `xml:"beforebreak"`
+			After  string `xml:"afterbreak"`
+		} `xml:"rule"`
+	} `xml:"body>languagerules>languagerule"`
+	LanguageMaps []struct {
+		Pattern string `xml:"languagepattern,attr"`
+		Name    string `xml:"languagerulename,attr"`
+	} `xml:"body>maprules>languagemap"`
+}
+
+// LoadSentenceRules reads rules from an SRX 2.0 file. Regular expressions are of Go regexp,
+// so lookarounds aren't supported; cascade is on unless the header says "no".
+func LoadSentenceRules(r io.Reader) (*SentenceRules, error) {
+	var doc srxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("srx: %w", err)
+	}
+
+	rules := &SentenceRules{
+		rules:   map[string][]sentenceRule{},
+		cascade: doc.Header.Cascade != "no",
+	}
+	for _, lr := range doc.LanguageRules {
+		for _, rule := range lr.Rules {
+			find, err := regexp.Compile(rule.Before)
+			if err != nil {
+				return nil, fmt.Errorf("srx: %s: beforebreak: %w", lr.Name, err)
+			}
+			after, err := regexp.Compile(`\A(?:` + rule.After + `)`)
+			if err != nil {
+				return nil, fmt.Errorf("srx: %s: afterbreak: %w", lr.Name, err)
+			}
+			rules.rules[lr.Name] = append(rules.rules[lr.Name], sentenceRule{
+				isBreak: rule.Break != "no",
+				find:    find,
+				before:  regexp.MustCompile(`(?:` + rule.Before + `)\z`),
+				after:   after,
+			})
+		}
+	}
+	for _, m := range doc.LanguageMaps {
+		pattern, err := regexp.Compile(`\A(?:` + m.Pattern + `)\z`)
+		if err != nil {
+			return nil, fmt.Errorf("srx: languagepattern: %w", err)
+		}
+		if _, ok := rules.rules[m.Name]; !ok {
+			return nil, fmt.Errorf("srx: unknown language rule %q", m.Name)
+		}
+		rules.maps = append(rules.maps, languageMap{pattern: pattern, rules: m.Name})
+	}
+	return rules, nil
+}
+
+var defaultRules = sync.OnceValue(func() *SentenceRules {
+	rules, err := LoadSentenceRules(strings.NewReader(string(defaultSentenceRules)))
+	if err != nil {
+		panic(err)
+	}
+	return rules
+})
+
+// DefaultSentenceRules returns the built-in rules, for English and Russian
+// and generic ones for other languages.
+func DefaultSentenceRules() *SentenceRules {
+	return defaultRules()
+}
+
+// languageRules returns rules for a language code like "en" or "ru-RU"
+func (r *SentenceRules) languageRules(lang string) []sentenceRule {
+	var rules []sentenceRule
+	for _, m := range r.maps {
+		if m.pattern.MatchString(lang) {
+			rules = append(rules, r.rules[m.rules]...)
+			if !r.cascade {
+				break
+			}
+		}
+	}
+	return rules
+}
+
+// Split splits text into sentences, without spaces around them. Placeholders, paired ones
+// with their text, are never split.
+func (r *SentenceRules) Split(lang, text string) []string {
+	rules := r.languageRules(lang)
+
+	// placeholders, from the start of an opening one to the end of the closing one
+	var spans [][2]int
+	opened := map[string]int{}
+	for _, m := range placeholderRegexp.FindAllStringSubmatchIndex(text, -1) {
+		switch {
+		case m[6] >= 0:
+			id := text[m[6]:m[7]]
+			if start, ok := opened[id]; ok {
+				spans = append(spans, [2]int{start, m[1]})
+				delete(opened, id)
+			}
+		case m[2] < 0:
+		case m[5] > m[4]:
+			spans = append(spans, [2]int{m[0], m[1]})
+		default:
+			opened[text[m[2]:m[3]]] = m[0]
+		}
+	}
+
+	var candidates []int
+	for _, rule := range rules {
+		if rule.isBreak {
+			for _, loc := range rule.find.FindAllStringIndex(text, -1) {
+				candidates = append(candidates, loc[1])
+			}
+		}
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	var sentences []string
+	last := 0
+	for _, pos := range candidates {
+		if pos <= last || pos >= len(text) || slices.ContainsFunc(spans, func(s [2]int) bool {
+			return s[0] < pos && pos < s[1]
+		}) {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.before.MatchString(text[:pos]) && rule.after.MatchString(text[pos:]) {
+				if rule.isBreak {
+					sentences = append(sentences, text[last:pos])
+					last = pos
+				}
+				break
+			}
+		}
+	}
+	sentences = append(sentences, text[last:])
+
+	var trimmed []string
+	for _, s := range sentences {
+		if s = strings.TrimSpace(s); s != "" {
+			trimmed = append(trimmed, s)
+		}
+	}
+	return trimmed
+}
+
+// SplitSegments splits segments into sentences; a sentence of a segment gets the ID
+// of the segment with "/1", "/2" and so on, and the placeholders it has.
+func (r *SentenceRules) SplitSegments(lang string, segments []Segment) []Segment {
+	var split []Segment
+	for _, s := range segments {
+		sentences := r.Split(lang, s.Text)
+		if len(sentences) <= 1 {
+			split = append(split, s)
+			continue
+		}
+		for i, sentence := range sentences {
+			ids := map[int]bool{}
+			for _, t := range SegmentTokens(sentence) {
+				ids[t.ID] = true
+			}
+			var placeholders []Placeholder
+			for _, p := range s.Placeholders {
+				if ids[p.ID] {
+					placeholders = append(placeholders, p)
+				}
+			}
+			split = append(split, Segment{
+				ID:           s.ID + "/" + strconv.Itoa(i+1),
+				Kind:         s.Kind,
+				Text:         sentence,
+				Placeholders: placeholders,
+				Line:         s.Line,
+			})
+		}
+	}
+	return split
+}
+
+// JoinSentences returns translations of segments made of translations of their sentences,
+// see SplitSegments; sentences without a translation are kept as they are.
+func JoinSentences(sentences []Segment, translations map[string]string) map[string]string {
+	joined := map[string]string{}
+	for id, translation := range translations {
+		joined[id] = translation
+	}
+
+	var parts []string
+	translated := false
+	for i, s := range sentences {
+		slash := strings.LastIndexByte(s.ID, '/')
+		if slash < 0 {
+			continue
+		}
+		translation, ok := translations[s.ID]
+		if !ok {
+			translation = s.Text
+		}
+		parts = append(parts, translation)
+		translated = translated || ok
+
+		if next := i + 1; next == len(sentences) || !strings.HasPrefix(sentences[next].ID, s.ID[:slash+1]) {
+			if translated {
+				joined[s.ID[:slash]] = strings.Join(parts, " ")
+			}
+			parts, translated = nil, false
+		}
+	}
+	return joined
+}
@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSentences(t *testing.T) {
+	rules := DefaultSentenceRules()
+	cases := []struct {
+		lang      string
+		text      string
+		sentences []string
+	}{
+		{"en", "One. Two? Three! Four", []string{"One.", "Two?", "Three!", "Four"}},
+		{"en", "Use tools, e.g. Go and Rust. Mr. Smith agrees.", []string{"Use tools, e.g. Go and Rust.", "Mr. Smith agrees."}},
+		{"en", "Pi is 3.14 here. Since go 1.21 it works. J. R. R. Tolkien wrote it.",
+			[]string{"Pi is 3.14 here.", "Since go 1.21 it works.", "J. R. R. Tolkien wrote it."}},
+		{"en", "He said \"stop.\" Then left. it was late.", []string{"He said \"stop.\"", "Then left. it was late."}},
+		{"en-US", "Call {1/}. {2}Then{/2} wait. {3/} is done.", []string{"Call {1/}.", "{2}Then{/2} wait.", "{3/} is done."}},
+		{"en", "{1}One. Two.{/1} Three.", []string{"{1}One. Two.{/1}", "Three."}},
+		{"en", "See {1}the docs.{/1} Next.", []string{"See {1}the docs.{/1}", "Next."}},
+		{"ru", "Это т.е. Москва. Улица им. Пушкина. См. рис. 5 ниже.",
+			[]string{"Это т.е. Москва.", "Улица им. Пушкина.", "См. рис. 5 ниже."}},
+		{"ru", "Поэт А. С. Пушкин родился в Москве. Версия 1.21 вышла.",
+			[]string{"Поэт А. С. Пушкин родился в Москве.", "Версия 1.21 вышла."}},
+		// no English abbreviations for Russian
+		{"ru", "Mr. Smith", []string{"Mr.", "Smith"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.sentences, rules.Split(c.lang, c.text), c.text)
+	}
+}
+
+func TestLoadSentenceRules(t *testing.T) {
+	rules, err := LoadSentenceRules(strings.NewReader(`<srx version="2.0" xmlns="http://www.lisa.org/srx20">
+  <header cascade="no"/>
+  <body>
+    <languagerules>
+      <languagerule languagerulename="Semicolon">
+        <rule break="no"><beforebreak>keep;\s*</beforebreak><afterbreak></afterbreak></rule>
+        <rule><beforebreak>;\s*</beforebreak><afterbreak>\S</afterbreak></rule>
+      </languagerule>
+      <languagerule languagerulename="Default">
+        <rule><beforebreak>\.\s+</beforebreak><afterbreak></afterbreak></rule>
+      </languagerule>
+    </languagerules>
+    <maprules>
+      <languagemap languagepattern="xx.*" languagerulename="Semicolon"/>
+      <languagemap languagepattern=".*" languagerulename="Default"/>
+    </maprules>
+  </body>
+</srx>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"a;", "keep; b. c;", "d"}, rules.Split("xx", "a; keep; b. c; d"))
+	assert.Equal(t, []string{"a; b.", "c"}, rules.Split("en", "a; b. c"))
+
+	_, err = LoadSentenceRules(strings.NewReader(`<srx><body><languagerules><languagerule languagerulename="X">` +
+		`<rule><beforebreak>(?&lt;=a)</beforebreak></rule></languagerule></languagerules></body></srx>`))
+	assert.ErrorContains(t, err, "srx: X: beforebreak: ")
+}
+
+func TestSplitSegments(t *testing.T) {
+	src := "Read *this. And that.* Then run `go test`. Done.\n\n# One sentence\n"
+	segments := Segments([]byte(src))
+	sentences := DefaultSentenceRules().SplitSegments("en", segments)
+
+	if !assert.Len(t, sentences, 4) {
+		return
+	}
+	assert.Equal(t, segments[0].ID+"/1", sentences[0].ID)
+	assert.Equal(t, "Read {1}this. And that.{/1}", sentences[0].Text)
+	assert.Equal(t, []Placeholder{segments[0].Placeholders[0]}, sentences[0].Placeholders)
+	assert.Equal(t, "Then run {2/}.", sentences[1].Text)
+	assert.Equal(t, []Placeholder{segments[0].Placeholders[1]}, sentences[1].Placeholders)
+	assert.Equal(t, segments[1], sentences[3])
+
+	translations := JoinSentences(sentences, map[string]string{
+		sentences[0].ID: "Прочти {1}это. И то.{/1}",
+		sentences[1].ID: "Затем запусти {2/}.",
+		sentences[3].ID: "Одно предложение",
+	})
+	var buf bytes.Buffer
+	assert.NoError(t, Translate([]byte(src), &buf, translations, WithMarkdownOptions(WithKeepSource(true))))
+	assert.Equal(t, "Прочти *это. И то.* Затем запусти `go test`. Done.\n\n# Одно предложение\n", buf.String())
+}
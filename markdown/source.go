@@ -0,0 +1,550 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// With Config.KeepSource, blocks nobody has changed are copied from the source byte to byte,
+// only changed ones are rendered. The md2md parser saves where every block starts on each of
+// its lines, after prefixes of its containers like "> ", so a block can be copied into a
+// container rendered anew with the padding of the render. A container with changes within keeps
+// its prefixes from the source, like "*   " of a list item, and a table keeps its cells which
+// haven't changed.
+//
+// The parser saves a fingerprint of every node too, changes are found by comparing nodes with
+// them, see MarkChanged.
+
+// offsets of lines of a block in the source, see sourceBlockParser
+const sourceRowsAttr = "md2md-source-rows"
+
+// a node changed by a transformer, see MarkChanged
+const changedAttr = "md2md-changed"
+
+// the parsed node, see saveFingerprints
+const fingerprintAttr = "md2md-fingerprint"
+
+// MarkChanged marks n as changed, so md2md renders it instead of copying its source with
+// Config.KeepSource. Changes of fields of nodes, their lines and children, are found without it,
+// as well as added nodes; it is for what md2md can't see, like a change of a node a field
+// points to, e.g. Info of a fenced code block.
+func MarkChanged(n ast.Node) {
+	setAttribute(n, n, changedAttr, true)
+}
+
+func isChanged(n ast.Node) bool {
+	_, ok := attribute(n, changedAttr)
+	return ok
+}
+
+// fingerprint is what a node is apart from its descendants: its fields, and its children
+type fingerprint struct {
+	own      string
+	children string
+}
+
+func fingerprintOf(n ast.Node) fingerprint {
+	var own strings.Builder
+	own.WriteString(n.Kind().String())
+
+	// exported fields of the node type, like Level of headings or Destination of links
+	if v := reflect.ValueOf(n); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		writeFields(&own, v.Elem())
+	}
+	switch n := n.(type) {
+	case *ast.Text:
+		fmt.Fprintf(&own, " %v %v %v", n.SoftLineBreak(), n.HardLineBreak(), n.IsRaw())
+	case *ast.String:
+		fmt.Fprintf(&own, " %v %v", n.IsRaw(), n.IsCode())
+	}
+	if n.Type() == ast.TypeBlock {
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			fmt.Fprintf(&own, " %d:%d", line.Start, line.Stop)
+		}
+	}
+	for _, attr := range n.Attributes() {
+		fmt.Fprintf(&own, " %s=%v", attr.Name, attr.Value)
+	}
+
+	var children strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		fmt.Fprintf(&children, "%p ", c)
+	}
+	return fingerprint{own: own.String(), children: children.String()}
+}
+
+// baseNodeTypes are embedded in every node, their fields are its links to others
+var baseNodeTypes = []reflect.Type{
+	reflect.TypeOf(ast.BaseNode{}), reflect.TypeOf(ast.BaseBlock{}), reflect.TypeOf(ast.BaseInline{}),
+}
+
+// writeFields writes exported fields of v, fields of embedded structs too, like Destination of
+// the baseLink of links; fmt prints values of unexported fields as well
+func writeFields(w *strings.Builder, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		switch {
+		case f.Anonymous && f.Type.Kind() == reflect.Struct && !slices.Contains(baseNodeTypes, f.Type):
+			writeFields(w, v.Field(i))
+		case f.IsExported() && !f.Anonymous:
+			fmt.Fprintf(w, " %s=%v", f.Name, v.Field(i))
+		}
+	}
+}
+
+// saveFingerprints saves the fingerprint of every node of the parsed document; it runs after
+// AST transformers of goldmark and extensions, which make the document md2md gets
+func saveFingerprints(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			setAttribute(n, n, fingerprintAttr, fingerprintOf(n))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// saveFingerprintsPriority goes before Transformers, see transformerPriority
+const saveFingerprintsPriority = transformerPriority - 1
+
+// nodeChanges tells if n has been changed or added, and if its children have been changed
+func nodeChanges(n ast.Node) (own bool, children bool) {
+	v, ok := attribute(n, fingerprintAttr)
+	if !ok {
+		return true, true
+	}
+	saved, current := v.(fingerprint), fingerprintOf(n)
+	return isChanged(n) || saved.own != current.own, saved.children != current.children
+}
+
+func sourceRows(n ast.Node) []int {
+	if v, ok := attribute(n, sourceRowsAttr); ok {
+		return v.([]int)
+	}
+	return nil
+}
+
+// addSourceRow adds a row of n, see setAttribute for in
+func addSourceRow(in, n ast.Node, row int) {
+	setAttribute(in, n, sourceRowsAttr, append(sourceRows(n), row))
+}
+
+// sourceBlockParser wraps a block parser to save the source rows of its blocks
+type sourceBlockParser struct {
+	parser.BlockParser
+}
+
+func (b *sourceBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	_, segment := reader.PeekLine()
+	last := pc.LastOpenedBlock().Node
+
+	// a definition takes lines of the paragraph before it as terms, removing the paragraph
+	var termRows []int
+	list, _ := parent.(*east.DefinitionList)
+	if list != nil && list.TemporaryParagraph != nil {
+		termRows = sourceRows(list.TemporaryParagraph)
+	}
+
+	node, state := b.BlockParser.Open(parent, reader, pc)
+	if node == nil {
+		return node, state
+	}
+	// like a setext heading, which takes lines of the paragraph
+	if paragraph, ok := last.(*ast.Paragraph); ok && state&parser.RequireParagraph != 0 {
+		setAttribute(parent, node, sourceRowsAttr, slices.Clone(sourceRows(paragraph)))
+	}
+	addSourceRow(parent, node, segment.Start)
+
+	if list != nil && len(termRows) > 0 {
+		var terms []ast.Node
+		for c := list.LastChild(); c != nil && c.Kind() == east.KindDefinitionTerm && sourceRows(c) == nil; c = c.PreviousSibling() {
+			terms = append([]ast.Node{c}, terms...)
+		}
+		if len(terms) == len(termRows) {
+			for i, term := range terms {
+				addSourceRow(list, term, termRows[i])
+			}
+		}
+	}
+	return node, state
+}
+
+func (b *sourceBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+
+	state := b.BlockParser.Continue(node, reader, pc)
+	// a closing line, like of fenced code blocks, is consumed; others belong to the next block
+	if _, after := reader.PeekLine(); state&parser.Close == 0 || line != nil && after.Start != segment.Start {
+		addSourceRow(node, node, segment.Start)
+	}
+	return state
+}
+
+// Close keeps source rows of blocks a parser replaces on close, like paragraphs of a tight list or
+// definition, which become text blocks
+func (b *sourceBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	// children and grandchildren
+	blocks := func() []ast.Node {
+		var nodes []ast.Node
+		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+			nodes = append(nodes, c)
+			for gc := c.FirstChild(); gc != nil; gc = gc.NextSibling() {
+				nodes = append(nodes, gc)
+			}
+		}
+		return nodes
+	}
+
+	// replaced ones are out of the document after it, rows of such nodes are lost
+	before := blocks()
+	rows := make([][]int, len(before))
+	for i, n := range before {
+		rows[i] = sourceRows(n)
+	}
+
+	b.BlockParser.Close(node, reader, pc)
+
+	after := blocks()
+	if len(after) != len(before) {
+		return
+	}
+	for i, n := range after {
+		if n != before[i] && sourceRows(n) == nil {
+			setAttribute(node, n, sourceRowsAttr, rows[i])
+		}
+	}
+}
+
+func (b *sourceBlockParser) SetOption(name parser.OptionName, value any) {
+	if so, ok := b.BlockParser.(parser.SetOptioner); ok {
+		so.SetOption(name, value)
+	}
+}
+
+// sourceParagraphTransformer wraps a paragraph transformer to pass source rows of the paragraph
+// to blocks made of its lines, like tables
+type sourceParagraphTransformer struct {
+	parser.ParagraphTransformer
+}
+
+func (t *sourceParagraphTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	rows := sourceRows(node)
+	parent, prev, next := node.Parent(), node.PreviousSibling(), node.NextSibling()
+	var lines []text.Segment
+	for i := 0; i < node.Lines().Len(); i++ {
+		lines = append(lines, node.Lines().At(i))
+	}
+
+	t.ParagraphTransformer.Transform(node, reader, pc)
+
+	if len(rows) != len(lines) {
+		return
+	}
+	// the paragraph keeps a part of its lines, blocks are made of lines before and after it
+	first, last := 0, len(lines)
+	if node.Parent() != nil {
+		if node.Lines().Len() == len(lines) {
+			return
+		}
+		first = slices.Index(lines, node.Lines().At(0))
+		last = first + node.Lines().Len()
+		if first < 0 || last > len(lines) {
+			return
+		}
+		setAttribute(node, node, sourceRowsAttr, slices.Clone(rows[first:last]))
+	}
+
+	var added []ast.Node
+	c := parent.FirstChild()
+	if prev != nil {
+		c = prev.NextSibling()
+	}
+	for ; c != nil && c != next; c = c.NextSibling() {
+		if c != ast.Node(node) {
+			added = append(added, c)
+		}
+	}
+	switch {
+	case len(added) == 1 && node.Parent() == nil:
+		setAttribute(added[0], added[0], sourceRowsAttr, slices.Clone(rows))
+	case len(added) == 1 && first > 0 && added[0].NextSibling() == node:
+		setAttribute(added[0], added[0], sourceRowsAttr, slices.Clone(rows[:first]))
+	case len(added) == 1 && last < len(lines) && node.NextSibling() == added[0]:
+		setAttribute(added[0], added[0], sourceRowsAttr, slices.Clone(rows[last:]))
+	}
+}
+
+func sourceBlockParsers(ps util.PrioritizedSlice) []util.PrioritizedValue {
+	var wrapped []util.PrioritizedValue
+	for _, v := range ps {
+		wrapped = append(wrapped, util.Prioritized(&sourceBlockParser{v.Value.(parser.BlockParser)}, v.Priority))
+	}
+	return wrapped
+}
+
+func sourceParagraphTransformers(ps util.PrioritizedSlice) []util.PrioritizedValue {
+	var wrapped []util.PrioritizedValue
+	for _, v := range ps {
+		wrapped = append(wrapped, util.Prioritized(
+			&sourceParagraphTransformer{v.Value.(parser.ParagraphTransformer)}, v.Priority))
+	}
+	return wrapped
+}
+
+// blockRows returns offsets of lines of n, its own and of its descendants, like lazy
+// continuation lines of a blockquote; trailing blank lines are left for the separator
+func blockRows(source []byte, n ast.Node) []int {
+	var rows []int
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Type() == ast.TypeBlock {
+			rows = append(rows, sourceRows(n)...)
+		}
+		return ast.WalkContinue, nil
+	})
+	slices.Sort(rows)
+
+	// the outermost block starts the line
+	var lines []int
+	for _, row := range rows {
+		if l := len(lines); l == 0 || bytes.IndexByte(source[lines[l-1]:row], '\n') >= 0 {
+			lines = append(lines, row)
+		}
+	}
+	for len(lines) > 0 && util.IsBlank(source[lines[len(lines)-1]:lineEnd(source, lines[len(lines)-1])]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEnd returns the offset of the newline of the line with pos, or the source end
+func lineEnd(source []byte, pos int) int {
+	if i := bytes.IndexByte(source[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(source)
+}
+
+// changedBlocks returns blocks to be rendered: changed ones, with changed inlines, added ones and
+// parts of blocks, like table rows, which have no source rows of their own; and blocks which keep
+// their source with changes within, see splicesSource
+func changedBlocks(source []byte, root ast.Node) (map[ast.Node]bool, map[ast.Node]bool) {
+	changed, spliced := map[ast.Node]bool{}, map[ast.Node]bool{}
+	within := map[ast.Node]bool{}
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		own, children := nodeChanges(n)
+		c := own || children
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			if visit(child) {
+				c = true
+			}
+		}
+		within[n] = c
+		if !c && n.Type() == ast.TypeBlock && len(blockRows(source, n)) == 0 {
+			changed[n] = true
+			// a block with no source at all is an added one
+			return nodeOffset(n) < 0
+		}
+		if !c {
+			return false
+		}
+
+		switch {
+		case own:
+			changed[n] = true
+		case n.Kind() == ast.KindDocument:
+			spliced[n] = true
+		case n.Kind() == east.KindTable:
+			if splicesTable(source, n, within) {
+				spliced[n] = true
+				for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+					for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+						changed[cell] = within[cell]
+					}
+				}
+			} else {
+				changed[n] = true
+			}
+		default:
+			if _, _, ok := sourcePrefix(source, n); ok {
+				spliced[n] = true
+			} else {
+				changed[n] = true
+			}
+		}
+		return c
+	}
+	visit(root)
+	return changed, spliced
+}
+
+// splicesTable tells if changed cells of table n can be written into its source lines: the
+// table has the lines of its rows only and the rows are as parsed
+func splicesTable(source []byte, n ast.Node, within map[ast.Node]bool) bool {
+	rows := 0
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		if own, children := nodeChanges(row); own || children {
+			return false
+		}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			if within[cell] && cell.Lines().Len() != 1 {
+				return false
+			}
+		}
+		rows++
+	}
+	// the delimiter row goes after the header
+	return len(blockRows(source, n)) == rows+1
+}
+
+// sourcePrefix returns what container n has before its children in the source, on its first line
+// and on the next ones, like "*   " and "    " of a list item; false if n isn't a container with
+// the first child on its first line
+func sourcePrefix(source []byte, n ast.Node) (string, string, bool) {
+	rows, first := sourceRows(n), n.FirstChild()
+	if len(rows) == 0 || first == nil || first.Type() != ast.TypeBlock {
+		return "", "", false
+	}
+	firstRows := blockRows(source, first)
+	if len(firstRows) == 0 || firstRows[0] < rows[0] || bytes.IndexByte(source[rows[0]:firstRows[0]], '\n') >= 0 {
+		return "", "", false
+	}
+	prefix := string(source[rows[0]:firstRows[0]])
+
+	// the padding is what goes before the content on the next lines
+	var inner []int
+	for c := first; c != nil; c = c.NextSibling() {
+		inner = append(inner, blockRows(source, c)...)
+	}
+	slices.Sort(inner)
+	for _, row := range rows[1:] {
+		i, _ := slices.BinarySearch(inner, row)
+		if i < len(inner) && bytes.IndexByte(source[row:inner[i]], '\n') < 0 &&
+			!util.IsBlank(source[inner[i]:lineEnd(source, inner[i])]) {
+			return prefix, string(source[row:inner[i]]), true
+		}
+	}
+	// no such lines, markers of the first one turn into spaces
+	padding := strings.Map(func(r rune) rune {
+		if r == '>' || r == '\t' {
+			return r
+		}
+		return ' '
+	}, prefix)
+	return prefix, padding, true
+}
+
+// keepsSource tells if n is copied from the source, see Config.KeepSource
+func (r *Renderer) keepsSource(w util.BufWriter, n ast.Node) bool {
+	state := r.context.state(w)
+	return state.changed != nil && !state.changed[n] && !state.spliced[n] &&
+		(n.Type() == ast.TypeBlock || n.Kind() == ast.KindDocument)
+}
+
+// splicesSource tells if n is a container with changes within which keeps its source prefixes, or
+// a table which keeps its source cells, see Config.KeepSource
+func (r *Renderer) splicesSource(w util.BufWriter, n ast.Node) bool {
+	return n != nil && r.context.state(w).spliced[n]
+}
+
+// writeSourcePrefix writes the prefix of a container which splices its source and pads next lines
+// with its source padding; the padding is removed on exit
+func (r *Renderer) writeSourcePrefix(w util.BufWriter, source []byte, n ast.Node, entering bool) {
+	if !entering {
+		r.context.PopStack(w)
+		return
+	}
+	prefix, padding, _ := sourcePrefix(source, n)
+	if first := n.FirstChild(); !r.keepsSource(w, first) {
+		// a rendered block starts with its text, a copied one with spaces before it
+		row := blockRows(source, first)[0]
+		prefix += string(source[row : row+util.TrimLeftSpaceLength(source[row:lineEnd(source, row)])])
+	}
+	_, _ = w.WriteString(prefix)
+	r.context.PushStack(w, padding)
+}
+
+// writeSource copies lines of n with the current padding instead of the source one
+func (r *Renderer) writeSource(w util.BufWriter, source []byte, n ast.Node) {
+	if n.Kind() == ast.KindDocument {
+		_, _ = w.Write(source)
+		return
+	}
+
+	for i, row := range blockRows(source, n) {
+		line := source[row:lineEnd(source, row)]
+		if i > 0 {
+			_ = w.WriteByte('\n')
+			if util.IsBlank(line) {
+				_, _ = w.WriteString(barePaddingLine(r.context, w))
+				continue
+			}
+			r.context.Pad(w)
+		}
+		_, _ = w.Write(line)
+	}
+}
+
+// writeSourceGap writes blank lines between n and next as in the source, the padding of the
+// render goes after them; false if there is something else between them
+func (r *Renderer) writeSourceGap(w util.BufWriter, source []byte, n, next ast.Node) bool {
+	rows, nextRows := blockRows(source, n), blockRows(source, next)
+	if len(rows) == 0 || len(nextRows) == 0 {
+		return false
+	}
+	end := lineEnd(source, rows[len(rows)-1])
+	if end >= nextRows[0] {
+		return false
+	}
+	// something has been between them, like a removed block
+	if len(bytes.Trim(source[end:nextRows[0]], " \t\r\n>")) > 0 {
+		return false
+	}
+	for i := bytes.Count(source[end:nextRows[0]], []byte{'\n'}); i > 1; i-- {
+		_, _ = w.WriteString("\n" + barePaddingLine(r.context, w))
+	}
+	_ = w.WriteByte('\n')
+	r.context.Pad(w)
+	return true
+}
+
+// writeSourceBefore writes what goes before the first block of a changed document, like blank lines
+func (r *Renderer) writeSourceBefore(w util.BufWriter, source []byte, root ast.Node) {
+	if root.Kind() != ast.KindDocument || r.keepsSource(w, root) || !root.HasChildren() {
+		return
+	}
+	if rows := blockRows(source, root.FirstChild()); len(rows) > 0 {
+		_, _ = w.Write(source[:rows[0]])
+	}
+}
+
+// writeSourceAfter writes what goes after the last block of a changed document, like the final newline
+func (r *Renderer) writeSourceAfter(w util.BufWriter, source []byte, root ast.Node) {
+	if root.Kind() != ast.KindDocument || r.keepsSource(w, root) || !root.HasChildren() {
+		return
+	}
+	rows := blockRows(source, root.LastChild())
+	if len(rows) == 0 {
+		return
+	}
+	after := source[lineEnd(source, rows[len(rows)-1]):]
+
+	last := root.LastChild()
+	for last.LastChild() != nil && last.LastChild().Type() == ast.TypeBlock {
+		last = last.LastChild()
+	}
+	if !r.keepsSource(w, last) && slices.Contains(lineEndingKinds, last.Kind()) {
+		// its node renderer has ended the line
+		after = bytes.TrimPrefix(after, []byte{'\n'})
+	}
+	_, _ = w.Write(after)
+}
@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// defaultStrikethroughLength is used when the source delimiter is unknown, e.g. for nodes added to the AST
+const defaultStrikethroughLength = 2
+
+// the number of tildes, ~ or ~~, the strikethrough has been written with
+const strikethroughLengthAttr = "md2md-strikethrough-length"
+
+// strikethroughLengthParser wraps extension.NewStrikethroughParser() to save the delimiter length
+// in strikethrough nodes, see emphasisMarkerParser
+type strikethroughLengthParser struct {
+	parser.InlineParser
+}
+
+func newStrikethroughLengthParser() parser.InlineParser {
+	return &strikethroughLengthParser{
+		InlineParser: extension.NewStrikethroughParser(),
+	}
+}
+
+func (p *strikethroughLengthParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	node := p.InlineParser.Parse(parent, block, pc)
+	if d, ok := node.(*parser.Delimiter); ok {
+		d.Processor = &strikethroughLengthProcessor{
+			DelimiterProcessor: d.Processor,
+			parent:             parent,
+		}
+	}
+	return node
+}
+
+// strikethroughLengthProcessor saves the number of delimiters the opener and the closer consume
+type strikethroughLengthProcessor struct {
+	parser.DelimiterProcessor
+	parent ast.Node
+}
+
+func (p *strikethroughLengthProcessor) OnMatch(consumes int) ast.Node {
+	node := p.DelimiterProcessor.OnMatch(consumes)
+	setAttribute(p.parent, node, strikethroughLengthAttr, consumes)
+	return node
+}
+
+func strikethroughLength(n ast.Node) int {
+	if v, ok := attribute(n, strikethroughLengthAttr); ok {
+		return v.(int)
+	}
+	return defaultStrikethroughLength
+}
+
+type strikethroughNodeRenderer struct {
+	*nodeRenderer
+}
+
+func newStrikethroughNodeRenderer(context *Context) NodeRenderer {
+	return &strikethroughNodeRenderer{newNodeRenderer(context)}
+}
+
+func (r *strikethroughNodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	reg.Register(east.KindStrikethrough, r.renderStrikethrough)
+}
+
+func (r *nodeRenderer) renderStrikethrough(
+	w util.BufWriter, source []byte, node ast.Node, _ bool) (ast.WalkStatus, error) {
+	_, _ = w.WriteString(strings.Repeat("~", strikethroughLength(node)))
+	return ast.WalkContinue, nil
+}
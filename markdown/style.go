@@ -0,0 +1,150 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// HeadingStyle is how md2md writes headings.
+type HeadingStyle int
+
+const (
+	// HeadingStyleSource keeps headings as written in the source
+	HeadingStyleSource HeadingStyle = iota
+
+	// HeadingStyleATX writes "# Heading"
+	HeadingStyleATX
+
+	// HeadingStyleSetext underlines headings of levels 1 and 2 with "===" and "---",
+	// other levels are ATX ones
+	HeadingStyleSetext
+)
+
+// HardLineBreakStyle is how md2md writes hard line breaks.
+type HardLineBreakStyle int
+
+const (
+	// HardLineBreakSource keeps hard line breaks as written in the source
+	HardLineBreakSource HardLineBreakStyle = iota
+
+	// HardLineBreakBackslash writes \ at the end of line
+	HardLineBreakBackslash
+
+	// HardLineBreakSpaces writes 2 spaces at the end of line
+	HardLineBreakSpaces
+)
+
+// defaultThematicBreak is used when the source one is unknown, e.g. for nodes added to the AST
+const defaultThematicBreak = "***"
+
+// thematic break line as written in the source, like "- - -"
+const thematicBreakAttr = "md2md-thematic-break"
+
+// thematicBreakParser wraps parser.NewThematicBreakParser() to save the source line,
+// ast.ThematicBreak has no segments
+type thematicBreakParser struct {
+	parser.BlockParser
+}
+
+func newThematicBreakParser() parser.BlockParser {
+	return &thematicBreakParser{
+		BlockParser: parser.NewThematicBreakParser(),
+	}
+}
+
+func (b *thematicBreakParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	node, state := b.BlockParser.Open(parent, reader, pc)
+	if node != nil {
+		setAttribute(parent, node, thematicBreakAttr, bytes.TrimSpace(line))
+	}
+	return node, state
+}
+
+func (r *nodeRenderer) thematicBreak(n ast.Node) []byte {
+	if r.ThematicBreak != "" {
+		return []byte(r.ThematicBreak)
+	}
+	if v, ok := attribute(n, thematicBreakAttr); ok {
+		return v.([]byte)
+	}
+	return []byte(defaultThematicBreak)
+}
+
+// isSpaceOrTab is for spaces which may go before and after heading markers
+func isSpaceOrTab(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// isATXHeading finds out the heading style from the source: "#" goes before ATX heading content
+// while setext heading content starts a line (after blockquote or list item prefixes)
+func isATXHeading(source []byte, n *ast.Heading) bool {
+	if n.Lines().Len() == 0 {
+		// setext heading can't be empty
+		return true
+	}
+
+	i := n.Lines().At(0).Start
+	for i > 0 && isSpaceOrTab(source[i-1]) {
+		i--
+	}
+	return i > 0 && source[i-1] == '#'
+}
+
+// setextUnderlineLength returns the length of the setext heading underline in the source,
+// it is the line after the heading content
+func setextUnderlineLength(source []byte, n *ast.Heading) int {
+	if n.Lines().Len() == 0 {
+		return 0
+	}
+
+	stop := n.Lines().At(n.Lines().Len() - 1).Stop
+	eol := bytes.IndexByte(source[stop-1:], '\n')
+	if eol < 0 {
+		return 0
+	}
+
+	i := stop - 1 + eol + 1
+	for i < len(source) && (isSpaceOrTab(source[i]) || source[i] == '>') {
+		i++
+	}
+	start := i
+	for i < len(source) && (source[i] == '=' || source[i] == '-') {
+		i++
+	}
+	return i - start
+}
+
+func (r *nodeRenderer) headingStyle(source []byte, n *ast.Heading) HeadingStyle {
+	style := r.HeadingStyle
+	if style == HeadingStyleSource {
+		style = HeadingStyleSetext
+		if isATXHeading(source, n) {
+			style = HeadingStyleATX
+		}
+	}
+
+	if n.Level > 2 {
+		return HeadingStyleATX
+	}
+	return style
+}
+
+func (r *nodeRenderer) hardLineBreak(source []byte, n *ast.Text) string {
+	style := r.HardLineBreakStyle
+	if style == HardLineBreakSource {
+		// the text segment ends before \ or trailing spaces
+		style = HardLineBreakBackslash
+		if stop := n.Segment.Stop; stop < len(source) && source[stop] == ' ' {
+			style = HardLineBreakSpaces
+		}
+	}
+
+	if style == HardLineBreakSpaces {
+		return "  \n"
+	}
+	return "\\\n"
+}
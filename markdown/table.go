@@ -0,0 +1,184 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+type tableNodeRenderer struct {
+	*nodeRenderer
+}
+
+func newTableNodeRenderer(context *Context) NodeRenderer {
+	return &tableNodeRenderer{newNodeRenderer(context)}
+}
+
+func (r *tableNodeRenderer) RegisterFuncs(reg NodeRendererFuncRegisterer) {
+	// rows and cells are rendered by renderTable
+	reg.Register(east.KindTable, r.renderTable)
+}
+
+// minimal delimiter row cell is 3 chars wide, like ":-:"
+const minTableColumnWidth = 3
+
+func (r *nodeRenderer) renderTableCell(w util.BufWriter, source []byte, n ast.Node) (string, error) {
+	s, err := r.context.RenderToString(w, func(w util.BufWriter) error {
+		return r.context.RenderChildren(w, source, n)
+	})
+	return escapeTablePipes(s), err
+}
+
+// escapeTablePipes escapes every | which is not escaped yet, see isEscaped; the table parser splits cells
+// by unescaped pipes before any inline parsing, so code spans and raw html need it too
+// (goldmark even drops \ before | inside code spans of a cell)
+func escapeTablePipes(s string) string {
+	if !strings.Contains(s, "|") {
+		return s
+	}
+
+	b := []byte(s)
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '|' && !isEscaped(b, i) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func tableDelimiter(alignment east.Alignment, width int) string {
+	switch alignment {
+	case east.AlignLeft:
+		return ":" + strings.Repeat("-", width-1)
+	case east.AlignRight:
+		return strings.Repeat("-", width-1) + ":"
+	case east.AlignCenter:
+		return ":" + strings.Repeat("-", width-2) + ":"
+	}
+	return strings.Repeat("-", width)
+}
+
+func padTableCell(s string, alignment east.Alignment, width int) string {
+	diff := width - utf8.RuneCountInString(s)
+	if diff <= 0 {
+		return s
+	}
+
+	switch alignment {
+	case east.AlignRight:
+		return strings.Repeat(" ", diff) + s
+	case east.AlignCenter:
+		left := diff / 2
+		return strings.Repeat(" ", left) + s + strings.Repeat(" ", diff-left)
+	}
+	return s + strings.Repeat(" ", diff)
+}
+
+// renderTable renders the whole table at once because column widths are needed
+// before the first row is written
+func (r *nodeRenderer) renderTable(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*east.Table)
+	if r.context.state(w).spliced[n] {
+		return ast.WalkSkipChildren, r.spliceTable(w, source, n)
+	}
+
+	columns := len(n.Alignments)
+	var rows [][]string
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		cells := make([]string, columns)
+		i := 0
+		for cell := row.FirstChild(); cell != nil && i < columns; cell = cell.NextSibling() {
+			s, err := r.renderTableCell(w, source, cell)
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			cells[i] = s
+			i++
+		}
+		rows = append(rows, cells)
+	}
+
+	widths := make([]int, columns)
+	for i := range widths {
+		widths[i] = minTableColumnWidth
+		if !r.TablePadding {
+			continue
+		}
+		for _, cells := range rows {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cells[i]))
+		}
+	}
+
+	writeRow := func(cells []string) {
+		_ = w.WriteByte('|')
+		for i, cell := range cells {
+			if r.TablePadding {
+				cell = padTableCell(cell, n.Alignments[i], widths[i])
+			}
+			_, _ = w.WriteString(" " + cell + " |")
+		}
+	}
+
+	for i, cells := range rows {
+		if i > 0 {
+			_ = w.WriteByte('\n')
+			r.context.Pad(w)
+		}
+		writeRow(cells)
+
+		if i == 0 {
+			delimiters := make([]string, columns)
+			for j, alignment := range n.Alignments {
+				delimiters[j] = tableDelimiter(alignment, widths[j])
+			}
+			_ = w.WriteByte('\n')
+			r.context.Pad(w)
+			writeRow(delimiters)
+		}
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// spliceTable copies lines of a table with Config.KeepSource, changed cells are rendered in place
+// of their source text
+func (r *nodeRenderer) spliceTable(w util.BufWriter, source []byte, n *east.Table) error {
+	changed := r.context.state(w).changed
+	var cells []ast.Node
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			if changed[cell] {
+				cells = append(cells, cell)
+			}
+		}
+	}
+
+	for i, row := range blockRows(source, n) {
+		if i > 0 {
+			_ = w.WriteByte('\n')
+			r.context.Pad(w)
+		}
+		pos, end := row, lineEnd(source, row)
+		for ; len(cells) > 0 && cells[0].Lines().At(0).Start < end; cells = cells[1:] {
+			segment := cells[0].Lines().At(0)
+			s, err := r.renderTableCell(w, source, cells[0])
+			if err != nil {
+				return err
+			}
+			_, _ = w.Write(source[pos:segment.Start])
+			_, _ = w.WriteString(s)
+			pos = segment.Stop
+		}
+		_, _ = w.Write(source[pos:end])
+	}
+	return nil
+}
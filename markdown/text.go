@@ -0,0 +1,174 @@
+package markdown
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// textNodeRenderer renders plain text: the text of inlines without markup, blocks separated
+// with blank lines, list items and table rows with newlines.
+type textNodeRenderer struct {
+	// states of renders in progress by their writers, goldmark passes the same writer
+	// to every node renderer of a Render call
+	states sync.Map
+}
+
+// textState is the state of a single Render call
+type textState struct {
+	// a block separator is written before the next text, so empty blocks don't leave blank lines
+	separator string
+	started   bool
+}
+
+func newTextNodeRenderer() renderer.NodeRenderer {
+	return &textNodeRenderer{}
+}
+
+func (r *textNodeRenderer) state(w util.BufWriter) *textState {
+	if v, ok := r.states.Load(w); ok {
+		return v.(*textState)
+	}
+	return &textState{}
+}
+
+func (r *textNodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindDocument, r.renderDocument)
+
+	// blocks
+	for _, kind := range []ast.NodeKind{ast.KindParagraph, ast.KindTextBlock, ast.KindHeading,
+		ast.KindBlockquote, ast.KindList, east.KindTable, east.KindDefinitionList, east.KindFootnoteList, east.KindFootnote} {
+		reg.Register(kind, r.renderBlock("\n\n"))
+	}
+	for _, kind := range []ast.NodeKind{ast.KindListItem, east.KindTableHeader, east.KindTableRow,
+		east.KindDefinitionTerm, east.KindDefinitionDescription} {
+		reg.Register(kind, r.renderBlock("\n"))
+	}
+	reg.Register(east.KindTableCell, r.renderBlock("\t"))
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+
+	// no text in them
+	for _, kind := range []ast.NodeKind{ast.KindThematicBreak, ast.KindHTMLBlock, ast.KindRawHTML,
+		KindLinkReferenceDefinitions, KindFrontMatter, east.KindFootnoteLink, east.KindFootnoteBacklink} {
+		reg.Register(kind, r.renderNothing)
+	}
+
+	// inlines
+	reg.Register(ast.KindText, r.renderText)
+	reg.Register(ast.KindString, r.renderString)
+	reg.Register(ast.KindAutoLink, r.renderAutoLink)
+	reg.Register(east.KindTaskCheckBox, r.renderTaskCheckBox)
+	for _, kind := range []ast.NodeKind{ast.KindEmphasis, ast.KindLink, ast.KindImage, ast.KindCodeSpan,
+		east.KindStrikethrough} {
+		reg.Register(kind, r.renderChildren)
+	}
+}
+
+func (r *textNodeRenderer) write(w util.BufWriter, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	state := r.state(w)
+	if state.started && state.separator != "" {
+		_, _ = w.WriteString(state.separator)
+	}
+	state.separator = ""
+	state.started = true
+	_, _ = w.Write(b)
+}
+
+func (r *textNodeRenderer) renderDocument(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		r.states.Store(w, &textState{})
+	} else {
+		r.states.Delete(w)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *textNodeRenderer) renderBlock(separator string) renderer.NodeRendererFunc {
+	return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if state := r.state(w); !entering && state.started {
+			// the outer block is left after the inner one, so its separator wins
+			state.separator = separator
+		}
+		return ast.WalkContinue, nil
+	}
+}
+
+func (r *textNodeRenderer) renderCodeBlock(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		var b []byte
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			b = append(b, line.Value(source)...)
+		}
+		r.write(w, bytes.TrimRight(b, "\n"))
+	}
+	return r.renderBlock("\n\n")(w, source, n, entering)
+}
+
+func (r *textNodeRenderer) renderNothing(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *textNodeRenderer) renderChildren(
+	w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
+func (r *textNodeRenderer) renderText(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Text)
+	b := n.Segment.Value(source)
+	if !n.IsRaw() {
+		b = util.UnescapePunctuations(b)
+		b = util.ResolveNumericReferences(b)
+		b = util.ResolveEntityNames(b)
+	}
+	r.write(w, b)
+	if n.SoftLineBreak() || n.HardLineBreak() {
+		r.write(w, []byte{'\n'})
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *textNodeRenderer) renderString(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		r.write(w, node.(*ast.String).Value)
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *textNodeRenderer) renderAutoLink(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		r.write(w, node.(*ast.AutoLink).Label(source))
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *textNodeRenderer) renderTaskCheckBox(
+	w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if node.(*east.TaskCheckBox).IsChecked {
+		r.write(w, []byte("[x] "))
+	} else {
+		r.write(w, []byte("[ ] "))
+	}
+	return ast.WalkContinue, nil
+}
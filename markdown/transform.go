@@ -0,0 +1,176 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Transformer changes the document after it is parsed and before it is rendered, e.g. for bulk
+// edits of markdown sources. What it changes is rendered with Config.KeepSource, see MarkChanged.
+type Transformer interface {
+	Transform(doc *ast.Document, source []byte)
+}
+
+// TransformerFunc is a func implementing Transformer.
+type TransformerFunc func(doc *ast.Document, source []byte)
+
+func (f TransformerFunc) Transform(doc *ast.Document, source []byte) {
+	f(doc, source)
+}
+
+// transformerPriority runs transformers after the ones of goldmark and its extensions
+const transformerPriority = 10000
+
+// astTransformers makes parser AST transformers of ts, in their order
+func astTransformers(ts []Transformer) []util.PrioritizedValue {
+	var transformers []util.PrioritizedValue
+	for i, t := range ts {
+		f := func(doc *ast.Document, reader text.Reader, _ parser.Context) {
+			t.Transform(doc, reader.Source())
+		}
+		transformers = append(transformers, util.Prioritized(mdTransformFunc(f), transformerPriority+i))
+	}
+	return transformers
+}
+
+// LinkPrefix rewrites destinations of links, images and link reference definitions which start
+// with from, so that they start with to.
+func LinkPrefix(from, to string) Transformer {
+	// a definition line: [label]: <destination or [label]: destination
+	definition := regexp.MustCompile(`(?m)^([ \t]*\[(?:[^\]\\]|\\.)+\]:[ \t]*<?)` + regexp.QuoteMeta(from))
+
+	return TransformerFunc(func(doc *ast.Document, source []byte) {
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch n := n.(type) {
+			case *ast.Link:
+				n.Destination = rewriteLinkPrefix(n, n.Destination, from, to)
+			case *ast.Image:
+				n.Destination = rewriteLinkPrefix(n, n.Destination, from, to)
+			case *LinkReferenceDefinitions:
+				raw := n.Text(source)
+				if rewritten := definition.ReplaceAll(raw, []byte("${1}"+to)); !bytes.Equal(rewritten, raw) {
+					n.Raw = rewritten
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+	})
+}
+
+// rewriteLinkPrefix returns the rewritten destination of n; the reference form of n
+// is kept as its definition is rewritten too
+func rewriteLinkPrefix(n ast.Node, destination []byte, from, to string) []byte {
+	if !bytes.HasPrefix(destination, []byte(from)) {
+		return destination
+	}
+	rewritten := append([]byte(to), destination[len(from):]...)
+	if v, ok := attribute(n, linkReferenceAttr); ok {
+		if ref := v.(*linkReference); bytes.Equal(ref.destination, destination) {
+			ref.destination = rewritten
+		}
+	}
+	return rewritten
+}
+
+// ShiftHeadings changes levels of headings by delta, within 1..6.
+func ShiftHeadings(delta int) Transformer {
+	return TransformerFunc(func(doc *ast.Document, _ []byte) {
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			heading, ok := n.(*ast.Heading)
+			if !entering || !ok {
+				return ast.WalkContinue, nil
+			}
+			heading.Level = min(max(heading.Level+delta, 1), 6)
+			return ast.WalkSkipChildren, nil
+		})
+	})
+}
+
+// ReplaceText replaces from with to in text, code spans included; markup is escaped
+// in the replaced text where needed.
+func ReplaceText(from, to string) Transformer {
+	return TransformerFunc(func(doc *ast.Document, source []byte) {
+		var texts []*ast.Text
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if t, ok := n.(*ast.Text); entering && ok && bytes.Contains(t.Segment.Value(source), []byte(from)) {
+				texts = append(texts, t)
+			}
+			return ast.WalkContinue, nil
+		})
+
+		for _, t := range texts {
+			value := t.Segment.Value(source)
+			if !t.IsRaw() {
+				value = util.UnescapePunctuations(value)
+				value = util.ResolveNumericReferences(value)
+				value = util.ResolveEntityNames(value)
+			}
+			s := ast.NewString(bytes.ReplaceAll(value, []byte(from), []byte(to)))
+			parent := t.Parent()
+			parent.ReplaceChild(parent, t, s)
+
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				// String has no line breaks, an empty text keeps the one of t
+				lineBreak := ast.NewTextSegment(text.NewSegment(t.Segment.Stop, t.Segment.Stop))
+				lineBreak.SetSoftLineBreak(t.SoftLineBreak())
+				lineBreak.SetHardLineBreak(t.HardLineBreak())
+				parent.InsertAfter(parent, s, lineBreak)
+			}
+		}
+	})
+}
+
+// DropHTMLComments removes HTML comments, blocks and inline ones.
+func DropHTMLComments() Transformer {
+	return TransformerFunc(func(doc *ast.Document, source []byte) {
+		var comments []ast.Node
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			switch n := n.(type) {
+			case *ast.HTMLBlock:
+				var b []byte
+				for i := 0; i < n.Lines().Len(); i++ {
+					line := n.Lines().At(i)
+					b = append(b, line.Value(source)...)
+				}
+				if n.HasClosure() {
+					b = append(b, n.ClosureLine.Value(source)...)
+				}
+				if isHTMLComment(b) {
+					comments = append(comments, n)
+				}
+			case *ast.RawHTML:
+				var b []byte
+				for i := 0; i < n.Segments.Len(); i++ {
+					segment := n.Segments.At(i)
+					b = append(b, segment.Value(source)...)
+				}
+				if isHTMLComment(b) {
+					comments = append(comments, n)
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+
+		for _, n := range comments {
+			parent := n.Parent()
+			parent.RemoveChild(parent, n)
+		}
+	})
+}
+
+func isHTMLComment(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return bytes.HasPrefix(b, []byte("<!--")) && bytes.HasSuffix(b, []byte("-->")) &&
+		bytes.Count(b, []byte("-->")) == 1
+}
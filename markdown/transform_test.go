@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark/ast"
+)
+
+func convertMD2MD(t *testing.T, src string, opts ...ConvertOption) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	assert.NoError(t, Convert([]byte(src), &buf, opts...))
+	return buf.String()
+}
+
+func TestTransformers(t *testing.T) {
+	cases := []struct {
+		name        string
+		transformer Transformer
+		src         string
+		dst         string
+	}{
+		{
+			name:        "link prefix",
+			transformer: LinkPrefix("/docs/", "https://example.com/docs/"),
+			src: "[a](/docs/a.md) and ![b](</docs/b c.png> \"B\") and [c] and [d](/blog/d)\n\n" +
+				"[c]: /docs/c.md\n[e]: </docs/e f>",
+			dst: "[a](https://example.com/docs/a.md) and ![b](<https://example.com/docs/b c.png> \"B\") and [c] and [d](/blog/d)\n\n" +
+				"[c]: https://example.com/docs/c.md\n[e]: <https://example.com/docs/e f>",
+		},
+		{
+			name:        "shift headings",
+			transformer: ShiftHeadings(1),
+			src:         "# A\n\nB\n-\n\n###### C\n\ntext",
+			dst:         "## A\n\n### B\n\n###### C\n\ntext",
+		},
+		{
+			name:        "shift headings up",
+			transformer: ShiftHeadings(-1),
+			src:         "# A\n\n### B",
+			dst:         "# A\n\n## B",
+		},
+		{
+			name:        "replace text",
+			transformer: ReplaceText("foo", "*bar*"),
+			src:         "foo and `foo`\n\n> foo  \n> and foo\n\n```\nfoo\n```",
+			dst:         "\\*bar\\* and `*bar*`\n\n> \\*bar\\*  \n> and \\*bar\\*\n\n```\nfoo\n```",
+		},
+		{
+			name:        "drop HTML comments",
+			transformer: DropHTMLComments(),
+			src:         "a <!-- inline --> b\n\n<!--\nblock\n-->\n\n<div>\n<!-- kept -->\n</div>\n\nc",
+			dst:         "a  b\n\n<div>\n<!-- kept -->\n</div>\n\nc",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.dst, convertMD2MD(t, c.src,
+				WithTransformers(c.transformer), WithMarkdownOptions(WithKeepSource(true))))
+		})
+	}
+}
+
+func TestTransformersOrder(t *testing.T) {
+	var order []string
+	transformer := func(name string) Transformer {
+		return TransformerFunc(func(doc *ast.Document, source []byte) {
+			order = append(order, name)
+		})
+	}
+	convertMD2MD(t, "a", WithTransformers(transformer("1"), transformer("2")), WithTransformers(transformer("3")))
+	assert.Equal(t, []string{"1", "2", "3"}, order)
+}
+
+func TestTransformersKeepSource(t *testing.T) {
+	// only what has been changed is rendered
+	src := "# Title\n\n*  item  one\n*  item  two\n\n<!-- note -->\n\n1.  [link](/docs/x)\n"
+	assert.Equal(t, "# Title\n\n*  item  one\n*  item  two\n\n1.  [link](/v2/docs/x)\n",
+		convertMD2MD(t, src, WithTransformers(DropHTMLComments(), LinkPrefix("/docs/", "/v2/docs/")),
+			WithMarkdownOptions(WithKeepSource(true))))
+}
@@ -0,0 +1,373 @@
+// Package po reads and writes gettext PO files of markdown documents, po4a-style: msgids are
+// segments of the source, see markdown.Segments, and Update merges them like msgmerge does.
+package po
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// wrapWidth is the width gettext tools wrap lines at
+const wrapWidth = 79
+
+// Entry is a message of a PO file.
+type Entry struct {
+	// TranslatorComments are "# " lines
+	TranslatorComments []string
+
+	// ExtractedComments are "#. " lines
+	ExtractedComments []string
+
+	// References are of "#: " lines, like "doc.md:12"
+	References []string
+
+	// Flags are of "#, " lines, like "fuzzy"
+	Flags []string
+
+	// PreviousID is the msgid a fuzzy translation has been made for, "#| msgid"
+	PreviousID string
+
+	Context string
+	ID      string
+	Str     string
+
+	// Obsolete entries are "#~ " ones, left from removed msgids
+	Obsolete bool
+}
+
+// IsFuzzy tells if e is marked fuzzy.
+func (e *Entry) IsFuzzy() bool {
+	for _, flag := range e.Flags {
+		if flag == "fuzzy" {
+			return true
+		}
+	}
+	return false
+}
+
+// File is a PO file; the header is the entry with the empty msgid.
+type File struct {
+	Entries []*Entry
+}
+
+// Header returns the header entry, nil if there is none.
+func (f *File) Header() *Entry {
+	for _, e := range f.Entries {
+		if e.ID == "" && e.Context == "" && !e.Obsolete {
+			return e
+		}
+	}
+	return nil
+}
+
+// Read parses a PO file. Plural forms aren't supported.
+func Read(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	var e *Entry
+	// the string a continuation line goes to, and the one of a "#|" line
+	var field, previous *string
+	var ignored string
+	finish := func() {
+		if e != nil {
+			f.Entries = append(f.Entries, e)
+		}
+		e, field, previous = nil, nil, nil
+	}
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			finish()
+			continue
+		}
+
+		obsolete := false
+		if rest, ok := strings.CutPrefix(line, "#~"); ok {
+			obsolete = true
+			line = strings.TrimSpace(rest)
+			// previous strings of obsolete entries, "#~| msgid"
+			if strings.HasPrefix(line, "|") {
+				line = "#" + line
+			}
+		}
+		// a comment after msgstr starts the next entry
+		if e != nil && strings.HasPrefix(line, "#") && field == &e.Str {
+			finish()
+		}
+		if e == nil {
+			e = &Entry{}
+		}
+		e.Obsolete = e.Obsolete || obsolete
+
+		switch {
+		case strings.HasPrefix(line, "#."):
+			e.ExtractedComments = append(e.ExtractedComments, strings.TrimSpace(line[2:]))
+		case strings.HasPrefix(line, "#:"):
+			e.References = append(e.References, strings.Fields(line[2:])...)
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(line[2:], ",") {
+				if flag = strings.TrimSpace(flag); flag != "" {
+					e.Flags = append(e.Flags, flag)
+				}
+			}
+		case strings.HasPrefix(line, "#|"):
+			// the previous msgid and its continuation lines, the previous msgctxt and
+			// msgid_plural aren't kept
+			rest := strings.TrimSpace(line[2:])
+			if !strings.HasPrefix(rest, `"`) {
+				keyword, value, _ := strings.Cut(rest, " ")
+				switch keyword {
+				case "msgid":
+					previous = &e.PreviousID
+				case "msgctxt", "msgid_plural":
+					previous = &ignored
+				default:
+					return nil, fmt.Errorf("po: line %d: unsupported keyword %s", lineNo, keyword)
+				}
+				*previous, rest = "", strings.TrimSpace(value)
+			} else if previous == nil {
+				return nil, fmt.Errorf("po: line %d: unexpected string", lineNo)
+			}
+			s, err := unquote(rest)
+			if err != nil {
+				return nil, fmt.Errorf("po: line %d: %w", lineNo, err)
+			}
+			*previous += s
+			field = nil
+		case strings.HasPrefix(line, "#"):
+			e.TranslatorComments = append(e.TranslatorComments, strings.TrimPrefix(line[1:], " "))
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("po: line %d: unexpected string", lineNo)
+			}
+			s, err := unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("po: line %d: %w", lineNo, err)
+			}
+			*field += s
+		default:
+			keyword, value, _ := strings.Cut(line, " ")
+			switch keyword {
+			case "msgctxt":
+				field = &e.Context
+			case "msgid":
+				field = &e.ID
+			case "msgstr":
+				field = &e.Str
+			default:
+				return nil, fmt.Errorf("po: line %d: unsupported keyword %s", lineNo, keyword)
+			}
+			s, err := unquote(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("po: line %d: %w", lineNo, err)
+			}
+			*field = s
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("po: %w", err)
+	}
+	finish()
+	return f, nil
+}
+
+// unquote reads a C string literal, as gettext tools write them, see escape
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("bad string %s", s)
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		c := s[i]
+		if c == '"' {
+			return "", fmt.Errorf("bad string %s", s)
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		if i++; i == len(s)-1 {
+			return "", fmt.Errorf("bad string %s", s)
+		}
+		switch c = s[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\', '"', '\'', '?':
+			b.WriteByte(c)
+		case 'x':
+			// hex digits, as many as there are
+			j := i + 1
+			for j < len(s)-1 && isHexDigit(s[j]) {
+				j++
+			}
+			n, err := strconv.ParseUint(s[i+1:j], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("bad string %s", s)
+			}
+			b.WriteByte(byte(n))
+			i = j - 1
+		default:
+			// up to 3 octal digits
+			j := i
+			for j < len(s)-1 && j < i+3 && '0' <= s[j] && s[j] <= '7' {
+				j++
+			}
+			n, err := strconv.ParseUint(s[i:j], 8, 8)
+			if err != nil {
+				return "", fmt.Errorf("bad string %s", s)
+			}
+			b.WriteByte(byte(n))
+			i = j - 1
+		}
+	}
+	return b.String(), nil
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return r.Replace(s)
+}
+
+// Write writes f wrapped as gettext tools do, so diffs are clean.
+func Write(w io.Writer, f *File) error {
+	var b bytes.Buffer
+	for i, e := range f.Entries {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		writeEntry(&b, e)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writeEntry(b *bytes.Buffer, e *Entry) {
+	for _, c := range e.TranslatorComments {
+		b.WriteString(strings.TrimRight("# "+c, " ") + "\n")
+	}
+	prefix := ""
+	if e.Obsolete {
+		prefix = "#~ "
+	} else {
+		for _, c := range e.ExtractedComments {
+			b.WriteString("#. " + c + "\n")
+		}
+		writeReferences(b, e.References)
+	}
+	if len(e.Flags) > 0 {
+		b.WriteString("#, " + strings.Join(e.Flags, ", ") + "\n")
+	}
+	if e.PreviousID != "" {
+		previousPrefix := "#| "
+		if e.Obsolete {
+			previousPrefix = "#~| "
+		}
+		writeString(b, previousPrefix, "msgid", e.PreviousID)
+	}
+	if e.Context != "" {
+		writeString(b, prefix, "msgctxt", e.Context)
+	}
+	writeString(b, prefix, "msgid", e.ID)
+	writeString(b, prefix, "msgstr", e.Str)
+}
+
+func writeReferences(b *bytes.Buffer, refs []string) {
+	line := ""
+	for _, ref := range refs {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(ref) > wrapWidth {
+			b.WriteString(line + "\n")
+			line = ""
+		}
+		if line == "" {
+			line = "#:"
+		}
+		line += " " + ref
+	}
+	if line != "" {
+		b.WriteString(line + "\n")
+	}
+}
+
+// writeString writes a keyword with a string, on one line if it fits and has no newlines
+// but the last one; otherwise on lines after an empty one, split after newlines and spaces
+func writeString(b *bytes.Buffer, prefix, keyword, s string) {
+	escaped := escape(s)
+	head := prefix + keyword + " "
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") &&
+		utf8.RuneCountInString(head)+utf8.RuneCountInString(escaped)+2 <= wrapWidth {
+		b.WriteString(head + `"` + escaped + "\"\n")
+		return
+	}
+
+	b.WriteString(head + "\"\"\n")
+	width := wrapWidth - utf8.RuneCountInString(prefix) - 2
+	for _, line := range wrap(escaped, width) {
+		b.WriteString(prefix + `"` + line + "\"\n")
+	}
+}
+
+// wrap splits escaped after \n and breaks lines longer than width after spaces
+func wrap(escaped string, width int) []string {
+	var lines []string
+	for len(escaped) > 0 {
+		part := escaped
+		for i := 0; i+1 < len(escaped); i++ {
+			if escaped[i] == '\\' {
+				if escaped[i+1] == 'n' {
+					part = escaped[:i+2]
+					break
+				}
+				// an escape sequence, like \\
+				i++
+			}
+		}
+		escaped = escaped[len(part):]
+
+		for utf8.RuneCountInString(part) > width {
+			// the last space within width, or the first one
+			cut, n := -1, 0
+			for i, r := range part {
+				if n >= width && cut > 0 {
+					break
+				}
+				if r == ' ' {
+					cut = i + 1
+				}
+				n++
+			}
+			if cut <= 0 || cut == len(part) {
+				break
+			}
+			lines = append(lines, part[:cut])
+			part = part[cut:]
+		}
+		lines = append(lines, part)
+	}
+	return lines
+}
@@ -0,0 +1,205 @@
+package po
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+func TestReadWrite(t *testing.T) {
+	src := `msgid ""
+msgstr ""
+"Language: ru\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+
+# translator
+#. type: Paragraph
+#: a.md:3 a.md:7
+#, fuzzy
+#| msgid "Old \"text\""
+msgid "New \"text\""
+msgstr "Новый «текст»"
+
+msgid ""
+"A long paragraph which doesn't fit into one line of a PO file, so it is "
+"wrapped after spaces.\n"
+"Then a line."
+msgstr ""
+
+#~ msgid "Removed"
+#~ msgstr "Удалено"
+
+#, fuzzy
+#~| msgid "Old removed"
+#~ msgid "Removed fuzzy"
+#~ msgstr "Удалено неточно"
+`
+	f, err := Read(strings.NewReader(src))
+	if !assert.NoError(t, err) || !assert.Len(t, f.Entries, 5) {
+		return
+	}
+	assert.Equal(t, "Language: ru\nContent-Type: text/plain; charset=UTF-8\n", f.Header().Str)
+	assert.Equal(t, &Entry{
+		TranslatorComments: []string{"translator"},
+		ExtractedComments:  []string{"type: Paragraph"},
+		References:         []string{"a.md:3", "a.md:7"},
+		Flags:              []string{"fuzzy"},
+		PreviousID:         `Old "text"`,
+		ID:                 `New "text"`,
+		Str:                "Новый «текст»",
+	}, f.Entries[1])
+	assert.True(t, f.Entries[1].IsFuzzy())
+	assert.Equal(t, "A long paragraph which doesn't fit into one line of a PO file, so it is wrapped after spaces.\nThen a line.",
+		f.Entries[2].ID)
+	assert.Equal(t, &Entry{ID: "Removed", Str: "Удалено", Obsolete: true}, f.Entries[3])
+	assert.Equal(t, &Entry{Flags: []string{"fuzzy"}, PreviousID: "Old removed", ID: "Removed fuzzy",
+		Str: "Удалено неточно", Obsolete: true}, f.Entries[4])
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, f))
+	assert.Equal(t, src, buf.String())
+
+	// previous strings of obsolete entries, as msgmerge --previous writes them
+	f, err = Read(strings.NewReader("#~| msgctxt \"old\"\n#~| msgid \"Old \"\n#~| \"removed\"\n" +
+		"#~ msgid \"Removed\"\n#~ msgstr \"\"\n"))
+	if assert.NoError(t, err) && assert.Len(t, f.Entries, 1) {
+		assert.Equal(t, &Entry{PreviousID: "Old removed", ID: "Removed", Obsolete: true}, f.Entries[0])
+	}
+
+	_, err = Read(strings.NewReader("msgid \"a\"\nmsgid_plural \"b\"\n"))
+	assert.EqualError(t, err, "po: line 2: unsupported keyword msgid_plural")
+}
+
+func TestUnquote(t *testing.T) {
+	for quoted, s := range map[string]string{
+		`"a\"b\\c"`:                   `a"b\c`,
+		`"it\'s\?"`:                   "it's?",
+		`"\t\n\r\a\v"`:                "\t\n\r\a\v",
+		`"\101\x42\0"`:                "AB\x00",
+		`"` + escape("{1}\n\"") + `"`: "{1}\n\"",
+	} {
+		u, err := unquote(quoted)
+		assert.NoError(t, err, quoted)
+		assert.Equal(t, s, u, quoted)
+	}
+
+	// escapes of Go only, unescaped quotes
+	for _, quoted := range []string{`"\u0041"`, `"\U00000041"`, `"a"b"`, `"a\"`} {
+		_, err := unquote(quoted)
+		assert.EqualError(t, err, "bad string "+quoted)
+	}
+}
+
+const source = "# Title\n\nThe first sentence. The `second` one.\n\nA paragraph to remove.\n"
+
+func update(t *testing.T, f *File, source string) *File {
+	t.Helper()
+	segments := markdown.DefaultSentenceRules().SplitSegments("en", markdown.Segments([]byte(source)))
+	return Update(f, "doc.md", "ru", segments)
+}
+
+func TestUpdate(t *testing.T) {
+	f := update(t, nil, source)
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, f))
+	assert.Equal(t, `msgid ""
+msgstr ""
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Language: ru\n"
+
+#. type: Heading
+#: doc.md:1
+msgid "Title"
+msgstr ""
+
+#. type: Paragraph
+#: doc.md:3
+msgid "The first sentence."
+msgstr ""
+
+#. type: Paragraph
+#. {1/}: `+"`second`"+`
+#: doc.md:3
+msgid "The {1/} one."
+msgstr ""
+
+#. type: Paragraph
+#: doc.md:5
+msgid "A paragraph to remove."
+msgstr ""
+`, buf.String())
+
+	for _, e := range f.Entries[1:] {
+		e.Str = map[string]string{
+			"Title":                  "Заголовок",
+			"The first sentence.":    "Первое предложение.",
+			"The {1/} one.":          "{1/} второе.",
+			"A paragraph to remove.": "Абзац на удаление.",
+		}[e.ID]
+	}
+
+	// the title is repeated, the first sentence is changed, the paragraph is removed
+	f = update(t, f, "# Title\n\nThe first sentence!\nThe `second` one.\n\nNew.\n\n# Title\n")
+	buf.Reset()
+	assert.NoError(t, Write(&buf, f))
+	assert.Equal(t, `msgid ""
+msgstr ""
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Language: ru\n"
+
+#. type: Heading
+#: doc.md:1 doc.md:8
+msgid "Title"
+msgstr "Заголовок"
+
+#. type: Paragraph
+#: doc.md:3
+#, fuzzy
+#| msgid "The first sentence."
+msgid "The first sentence!"
+msgstr "Первое предложение."
+
+#. type: Paragraph
+#. {1/}: `+"`second`"+`
+#: doc.md:3
+msgid "The {1/} one."
+msgstr "{1/} второе."
+
+#. type: Paragraph
+#: doc.md:6
+msgid "New."
+msgstr ""
+
+#~ msgid "A paragraph to remove."
+#~ msgstr "Абзац на удаление."
+`, buf.String())
+
+	// fuzzy translations aren't applied
+	src := []byte("# Title\n\nThe first sentence!\nThe `second` one.\n\nNew.\n\n# Title\n")
+	segments := markdown.DefaultSentenceRules().SplitSegments("en", markdown.Segments(src))
+	var out bytes.Buffer
+	assert.NoError(t, markdown.Translate(src, &out, f.Translations(segments),
+		markdown.WithMarkdownOptions(markdown.WithKeepSource(true))))
+	assert.Equal(t, "# Заголовок\n\nThe first sentence! `second` второе.\n\nNew.\n\n# Заголовок\n", out.String())
+
+	// the removed paragraph is back
+	f = update(t, f, source)
+	assert.Equal(t, "Абзац на удаление.", f.Entries[4].Str)
+	assert.False(t, f.Entries[4].Obsolete)
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("", ""))
+	assert.Equal(t, 1.0, similarity("текст", "текст"))
+	assert.InDelta(t, 0.8, similarity("текст", "тексТ"), 1e-9)
+	assert.InDelta(t, 0.25, similarity("a", "abcd"), 1e-9)
+}
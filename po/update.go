@@ -0,0 +1,199 @@
+package po
+
+import (
+	"fmt"
+	"strings"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+// fuzzyThreshold is the least similarity of a changed msgid to its previous one, see similarity
+const fuzzyThreshold = 0.6
+
+const headerStr = "MIME-Version: 1.0\n" +
+	"Content-Type: text/plain; charset=UTF-8\n" +
+	"Content-Transfer-Encoding: 8bit\n"
+
+// Update returns f with msgids of segments of source, in their order; f may be nil for a new file.
+// Translations of msgids which are still there are kept; a changed msgid gets the translation of
+// the most similar old one marked fuzzy, and translations of removed msgids become obsolete.
+func Update(f *File, source string, lang string, segments []markdown.Segment) *File {
+	if f == nil {
+		f = &File{}
+	}
+
+	header := f.Header()
+	if header == nil {
+		header = &Entry{Str: headerStr}
+	}
+	if lang != "" {
+		header.Str = setHeaderField(header.Str, "Language", lang)
+	}
+	updated := &File{Entries: []*Entry{header}}
+
+	// msgids of the source, repeated ones are one entry
+	entries := map[string]*Entry{}
+	for _, s := range segments {
+		e, ok := entries[s.Text]
+		if !ok {
+			e = &Entry{ID: s.Text, ExtractedComments: extractedComments(s)}
+			entries[s.Text] = e
+			updated.Entries = append(updated.Entries, e)
+		}
+		if s.Line > 0 {
+			e.References = append(e.References, fmt.Sprintf("%s:%d", source, s.Line))
+		} else {
+			e.References = append(e.References, source)
+		}
+	}
+
+	old := map[string]*Entry{}
+	for _, e := range f.Entries {
+		if e != header && e.Context == "" {
+			old[e.ID] = e
+		}
+	}
+	used := map[*Entry]bool{}
+
+	var changed []*Entry
+	for _, e := range updated.Entries[1:] {
+		o, ok := old[e.ID]
+		if !ok {
+			changed = append(changed, e)
+			continue
+		}
+		used[o] = true
+		e.Str, e.TranslatorComments, e.Flags = o.Str, o.TranslatorComments, o.Flags
+		if e.IsFuzzy() {
+			e.PreviousID = o.PreviousID
+		}
+	}
+
+	for _, e := range changed {
+		var best *Entry
+		bestSimilarity := fuzzyThreshold
+		for _, o := range f.Entries {
+			if o == header || used[o] || o.Str == "" || o.Context != "" {
+				continue
+			}
+			if sim := similarity(e.ID, o.ID); sim >= bestSimilarity {
+				best, bestSimilarity = o, sim
+			}
+		}
+		if best == nil {
+			continue
+		}
+		used[best] = true
+		e.Str, e.TranslatorComments = best.Str, best.TranslatorComments
+		e.Flags = append([]string{"fuzzy"}, without(best.Flags, "fuzzy")...)
+		e.PreviousID = best.ID
+	}
+
+	for _, o := range f.Entries {
+		if o == header || used[o] || o.Str == "" {
+			continue
+		}
+		updated.Entries = append(updated.Entries, &Entry{
+			TranslatorComments: o.TranslatorComments,
+			Flags:              o.Flags,
+			Context:            o.Context,
+			ID:                 o.ID,
+			Str:                o.Str,
+			Obsolete:           true,
+		})
+	}
+	return updated
+}
+
+// Translations returns translations of segments for markdown.Translate, fuzzy ones
+// are left out as gettext does.
+func (f *File) Translations(segments []markdown.Segment) map[string]string {
+	strs := map[string]string{}
+	for _, e := range f.Entries {
+		if !e.Obsolete && !e.IsFuzzy() && e.ID != "" && e.Str != "" && e.Context == "" {
+			strs[e.ID] = e.Str
+		}
+	}
+
+	translations := map[string]string{}
+	for _, s := range segments {
+		if str, ok := strs[s.Text]; ok {
+			translations[s.ID] = str
+		}
+	}
+	return markdown.JoinSentences(segments, translations)
+}
+
+func extractedComments(s markdown.Segment) []string {
+	comments := []string{"type: " + s.Kind}
+	for _, p := range s.Placeholders {
+		if p.Paired {
+			comments = append(comments, fmt.Sprintf("{%d}...{/%d}: %s...%s", p.ID, p.ID, p.Start, p.End))
+		} else {
+			comments = append(comments, fmt.Sprintf("{%d/}: %s", p.ID, p.Start))
+		}
+	}
+	for i, c := range comments {
+		// a comment is one line
+		comments[i] = strings.ReplaceAll(c, "\n", `\n`)
+	}
+	return comments
+}
+
+// setHeaderField sets a "Name: value" line of the header
+func setHeaderField(header, name, value string) string {
+	lines := strings.SplitAfter(header, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, name+":") {
+			lines[i] = name + ": " + value + "\n"
+			return strings.Join(lines, "")
+		}
+	}
+	if header != "" && !strings.HasSuffix(header, "\n") {
+		header += "\n"
+	}
+	return header + name + ": " + value + "\n"
+}
+
+func without(flags []string, flag string) []string {
+	var rest []string
+	for _, f := range flags {
+		if f != flag {
+			rest = append(rest, f)
+		}
+	}
+	return rest
+}
+
+// similarity is 1 minus the edit distance of runes of a and b divided by the longer length
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra) == 0 {
+		return 1
+	}
+	// the distance is at least the difference of lengths
+	if float64(len(rb))/float64(len(ra)) < fuzzyThreshold {
+		return float64(len(rb)) / float64(len(ra))
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(len(ra))
+}
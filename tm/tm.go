@@ -0,0 +1,211 @@
+// Package tm is a translation memory of a project kept in a file: translations of segments,
+// see markdown.Segment, by language pairs, with TMX 1.4b import and export.
+package tm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+// storeVersion is the version of the file format
+const storeVersion = 1
+
+// Unit is a translation of a source text from SrcLang to TrgLang.
+type Unit struct {
+	SrcLang string `json:"srcLang"`
+	TrgLang string `json:"trgLang"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Changed   time.Time `json:"changed"`
+	ChangedBy string    `json:"changedBy,omitempty"`
+}
+
+// Memory is a translation memory with one unit per language pair and source text.
+type Memory struct {
+	path  string
+	units []*Unit
+	index map[unitKey]*Unit
+}
+
+type unitKey struct {
+	srcLang, trgLang, source string
+}
+
+func key(srcLang, trgLang, source string) unitKey {
+	return unitKey{strings.ToLower(srcLang), strings.ToLower(trgLang), source}
+}
+
+type storeFile struct {
+	Version int     `json:"version"`
+	Units   []*Unit `json:"units"`
+}
+
+// Open reads the memory of the file at path, an empty one if there is no file yet.
+func Open(path string) (*Memory, error) {
+	m := &Memory{path: path, index: map[unitKey]*Unit{}}
+
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var store storeFile
+	if err := json.Unmarshal(dat, &store); err != nil {
+		return nil, fmt.Errorf("tm: %s: %w", path, err)
+	}
+	if store.Version != storeVersion {
+		return nil, fmt.Errorf("tm: %s: unsupported version %d", path, store.Version)
+	}
+	for _, u := range store.Units {
+		m.add(u)
+	}
+	return m, nil
+}
+
+func (m *Memory) add(u *Unit) {
+	k := key(u.SrcLang, u.TrgLang, u.Source)
+	if _, ok := m.index[k]; !ok {
+		m.units = append(m.units, u)
+	}
+	m.index[k] = u
+}
+
+// Save writes the memory to its file, replacing it at once.
+func (m *Memory) Save() error {
+	dat, err := json.MarshalIndent(storeFile{Version: storeVersion, Units: m.units}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp makes it 0600
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(dat, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.path)
+}
+
+// Get returns the translation of source; language codes are case-insensitive.
+func (m *Memory) Get(srcLang, trgLang, source string) (*Unit, bool) {
+	u, ok := m.index[key(srcLang, trgLang, source)]
+	return u, ok
+}
+
+// Put adds u or changes the target of the unit with its source. Zero times of u are now; the
+// creation of a changed unit is kept. It returns false if the memory has u already.
+func (m *Memory) Put(u Unit) bool {
+	now := time.Now().UTC().Truncate(time.Second)
+	if u.Changed.IsZero() {
+		u.Changed = now
+	}
+	if u.ChangedBy == "" {
+		u.ChangedBy = u.CreatedBy
+	}
+
+	if old, ok := m.Get(u.SrcLang, u.TrgLang, u.Source); ok {
+		if old.Target == u.Target {
+			return false
+		}
+		old.Target, old.Changed, old.ChangedBy = u.Target, u.Changed, u.ChangedBy
+		return true
+	}
+
+	if u.Created.IsZero() {
+		u.Created = u.Changed
+	}
+	m.add(&u)
+	return true
+}
+
+// Units returns units of a language pair in the order they were added, all of them for empty
+// languages.
+func (m *Memory) Units(srcLang, trgLang string) []*Unit {
+	var units []*Unit
+	for _, u := range m.units {
+		if (srcLang == "" || strings.EqualFold(u.SrcLang, srcLang)) &&
+			(trgLang == "" || strings.EqualFold(u.TrgLang, trgLang)) {
+			units = append(units, u)
+		}
+	}
+	return units
+}
+
+// PairStats are statistics of a language pair.
+type PairStats struct {
+	SrcLang string
+	TrgLang string
+
+	Units       int
+	SourceWords int
+	TargetWords int
+
+	// LastChanged is the latest change of its units
+	LastChanged time.Time
+}
+
+// Stats returns statistics of language pairs, sorted by them.
+func (m *Memory) Stats() []PairStats {
+	pairs := map[[2]string]*PairStats{}
+	for _, u := range m.units {
+		k := [2]string{strings.ToLower(u.SrcLang), strings.ToLower(u.TrgLang)}
+		s, ok := pairs[k]
+		if !ok {
+			s = &PairStats{SrcLang: k[0], TrgLang: k[1]}
+			pairs[k] = s
+		}
+		s.Units++
+		s.SourceWords += words(u.Source)
+		s.TargetWords += words(u.Target)
+		if u.Changed.After(s.LastChanged) {
+			s.LastChanged = u.Changed
+		}
+	}
+
+	var stats []PairStats
+	for _, s := range pairs {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b PairStats) int {
+		return strings.Compare(a.SrcLang+" "+a.TrgLang, b.SrcLang+" "+b.TrgLang)
+	})
+	return stats
+}
+
+// words counts words of the text of a segment, placeholders aren't words
+func words(text string) int {
+	n := 0
+	for _, t := range markdown.SegmentTokens(text) {
+		if t.Kind == markdown.TokenText {
+			n += len(strings.Fields(t.Text))
+		}
+	}
+	return n
+}
@@ -0,0 +1,130 @@
+package tm
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	created = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	changed = time.Date(2026, 10, 2, 8, 30, 0, 0, time.UTC)
+)
+
+func TestMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.tm")
+	m, err := Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, m.Put(Unit{SrcLang: "en", TrgLang: "ru", Source: "Run {1/}.", Target: "Запусти {1/}.",
+		Created: created, CreatedBy: "alice"}))
+	assert.True(t, m.Put(Unit{SrcLang: "en", TrgLang: "de", Source: "Run {1/}.", Target: "Starte {1/}."}))
+	assert.False(t, m.Put(Unit{SrcLang: "EN", TrgLang: "RU", Source: "Run {1/}.", Target: "Запусти {1/}."}))
+	assert.True(t, m.Put(Unit{SrcLang: "en", TrgLang: "ru", Source: "Run {1/}.", Target: "Выполни {1/}.",
+		Changed: changed, ChangedBy: "bob"}))
+	assert.NoError(t, m.Save())
+
+	m, err = Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	u, ok := m.Get("en", "ru", "Run {1/}.")
+	if assert.True(t, ok) {
+		assert.Equal(t, &Unit{SrcLang: "en", TrgLang: "ru", Source: "Run {1/}.", Target: "Выполни {1/}.",
+			Created: created, CreatedBy: "alice", Changed: changed, ChangedBy: "bob"}, u)
+	}
+	assert.Len(t, m.Units("", ""), 2)
+	assert.Len(t, m.Units("en", "ru"), 1)
+
+	stats := m.Stats()
+	if assert.Len(t, stats, 2) {
+		assert.Equal(t, "de", stats[0].TrgLang)
+		assert.Equal(t, PairStats{SrcLang: "en", TrgLang: "ru", Units: 1, SourceWords: 2, TargetWords: 2,
+			LastChanged: changed}, stats[1])
+	}
+}
+
+func TestTMX(t *testing.T) {
+	m, _ := Open(filepath.Join(t.TempDir(), "project.tm"))
+	m.Put(Unit{SrcLang: "en", TrgLang: "ru", Source: "Hello {1}world{/1} & {2/}", Target: "{1}Мир{/1}, привет {{}",
+		Created: created, CreatedBy: "alice", Changed: changed, ChangedBy: "bob"})
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.ExportTMX(&buf, "en", "ru"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="ctb" creationtoolversion="1" segtype="sentence" o-tmf="ctb" adminlang="en" srclang="en" datatype="plaintext"/>
+  <body>
+    <tu creationdate="20261001T120000Z" creationid="alice" changedate="20261002T083000Z" changeid="bob">
+      <tuv xml:lang="en"><seg>Hello <bpt i="1" x="1"></bpt>world<ept i="1"></ept> &amp; <ph x="2"></ph></seg></tuv>
+      <tuv xml:lang="ru"><seg><bpt i="1" x="1"></bpt>Мир<ept i="1"></ept>, привет {}</seg></tuv>
+    </tu>
+  </body>
+</tmx>
+`, buf.String())
+
+	imported, _ := Open(filepath.Join(t.TempDir(), "imported.tm"))
+	stats, err := imported.ImportTMX(&buf, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, ImportStats{Added: 1}, stats)
+	assert.Equal(t, m.Units("", ""), imported.Units("", ""))
+}
+
+func TestImportTMX(t *testing.T) {
+	m, _ := Open(filepath.Join(t.TempDir(), "project.tm"))
+	m.Put(Unit{SrcLang: "en-US", TrgLang: "de", Source: "Save", Target: "Sichern"})
+
+	stats, err := m.ImportTMX(strings.NewReader(`<tmx version="1.4">
+<header srclang="en-US" creationtool="x" creationtoolversion="1" segtype="sentence" o-tmf="x" adminlang="en" datatype="html"/>
+<body>
+  <tu creationdate="20261001T120000Z">
+    <prop type="x-note">a note</prop>
+    <tuv xml:lang="EN-US"><seg>Click <bpt i="5">&lt;b&gt;</bpt><hi>Save</hi><ept i="5">&lt;/b&gt;</ept><ph>&lt;br/&gt;</ph></seg></tuv>
+    <tuv xml:lang="ru-RU" changedate="20261003T000000Z" changeid="dave"><seg>Нажми <bpt i="5">&lt;b&gt;</bpt>Сохранить<ept i="5">&lt;/b&gt;</ept><ph>&lt;br/&gt;</ph></seg></tuv>
+  </tu>
+  <tu>
+    <tuv xml:lang="en-US"><seg>Save</seg></tuv>
+    <tuv xml:lang="de"><seg>Speichern</seg></tuv>
+    <tuv xml:lang="fr"><seg></seg></tuv>
+  </tu>
+  <tu><tuv xml:lang="de"><seg>Nur</seg></tuv></tu>
+</body>
+</tmx>`), "erin")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ImportStats{Added: 1, Changed: 1, Skipped: 2}, stats)
+
+	u, ok := m.Get("en-us", "ru-ru", "Click {1}Save{/1}{2/}")
+	if assert.True(t, ok) {
+		assert.Equal(t, "Нажми {1}Сохранить{/1}{2/}", u.Target)
+		assert.Equal(t, created, u.Created)
+		assert.Equal(t, "erin", u.CreatedBy)
+		assert.Equal(t, time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), u.Changed)
+		assert.Equal(t, "dave", u.ChangedBy)
+	}
+	u, _ = m.Get("en-US", "de", "Save")
+	assert.Equal(t, "Speichern", u.Target)
+
+	// codes without x are numbered after the ones with it, the older unit is kept
+	stats, err = m.ImportTMX(strings.NewReader(`<tmx version="1.4"><header srclang="en"/><body>
+  <tu><tuv xml:lang="en"><seg><bpt i="1">&lt;b&gt;</bpt>A<ept i="1">&lt;/b&gt;</ept> <ph x="1"/></seg></tuv>
+    <tuv xml:lang="de"><seg><bpt i="1">&lt;b&gt;</bpt>B<ept i="1">&lt;/b&gt;</ept> <ph x="1"/></seg></tuv></tu>
+  <tu srclang="en-US" changedate="20261001T000000Z"><tuv xml:lang="EN-US"><seg>Save</seg></tuv><tuv xml:lang="de"><seg>Alt</seg></tuv></tu>
+</body></tmx>`), "")
+	assert.NoError(t, err)
+	assert.Equal(t, ImportStats{Added: 1, Older: 1}, stats)
+	u, _ = m.Get("en", "de", "{2}A{/2} {1/}")
+	assert.Equal(t, "{2}B{/2} {1/}", u.Target)
+	u, _ = m.Get("en-US", "de", "Save")
+	assert.Equal(t, "Speichern", u.Target)
+
+	_, err = m.ImportTMX(strings.NewReader(`<tmx><body><tu creationdate="yesterday"/></body></tmx>`), "")
+	assert.ErrorContains(t, err, "tmx: <tu creationdate>: ")
+}
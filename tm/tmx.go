@@ -0,0 +1,345 @@
+package tm
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+// tmxTime is the date format of TMX: 20061231T150405Z
+const tmxTime = "20060102T150405Z"
+
+// allLanguages is srclang of TMX with any language as the source
+const allLanguages = "*all*"
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ExportTMX writes units of a language pair as TMX 1.4b, all of them for empty languages;
+// placeholders of segments are <bpt>, <ept> and <ph>.
+func (m *Memory) ExportTMX(w io.Writer, srcLang, trgLang string) error {
+	header := srcLang
+	if header == "" {
+		header = allLanguages
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<tmx version=\"1.4\">\n")
+	fmt.Fprintf(&b, "  <header creationtool=\"ctb\" creationtoolversion=\"1\" segtype=\"sentence\" o-tmf=\"ctb\""+
+		" adminlang=\"en\" srclang=\"%s\" datatype=\"plaintext\"/>\n", escape(header))
+	b.WriteString("  <body>\n")
+
+	for _, u := range m.Units(srcLang, trgLang) {
+		b.WriteString("    <tu")
+		if header == allLanguages {
+			fmt.Fprintf(&b, " srclang=\"%s\"", escape(u.SrcLang))
+		}
+		fmt.Fprintf(&b, " creationdate=\"%s\"", u.Created.UTC().Format(tmxTime))
+		if u.CreatedBy != "" {
+			fmt.Fprintf(&b, " creationid=\"%s\"", escape(u.CreatedBy))
+		}
+		fmt.Fprintf(&b, " changedate=\"%s\"", u.Changed.UTC().Format(tmxTime))
+		if u.ChangedBy != "" {
+			fmt.Fprintf(&b, " changeid=\"%s\"", escape(u.ChangedBy))
+		}
+		b.WriteString(">\n")
+		fmt.Fprintf(&b, "      <tuv xml:lang=\"%s\"><seg>%s</seg></tuv>\n", escape(u.SrcLang), inline(u.Source))
+		fmt.Fprintf(&b, "      <tuv xml:lang=\"%s\"><seg>%s</seg></tuv>\n", escape(u.TrgLang), inline(u.Target))
+		b.WriteString("    </tu>\n")
+	}
+	b.WriteString("  </body>\n</tmx>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// inline returns the text of a segment with placeholders as TMX inline elements
+func inline(text string) string {
+	var b strings.Builder
+	for _, t := range markdown.SegmentTokens(text) {
+		switch t.Kind {
+		case markdown.TokenText:
+			b.WriteString(escape(t.Text))
+		case markdown.TokenOpen:
+			fmt.Fprintf(&b, `<bpt i="%d" x="%d"></bpt>`, t.ID, t.ID)
+		case markdown.TokenClose:
+			fmt.Fprintf(&b, `<ept i="%d"></ept>`, t.ID)
+		case markdown.TokenStandalone:
+			fmt.Fprintf(&b, `<ph x="%d"></ph>`, t.ID)
+		}
+	}
+	return b.String()
+}
+
+// ImportStats tells what ImportTMX has done.
+type ImportStats struct {
+	Added   int
+	Changed int
+	Skipped int
+
+	// Older are units changed before the ones of the memory, which are kept
+	Older int
+}
+
+// tuv is a variant of a TMX unit
+type tuv struct {
+	lang              string
+	text              string
+	created, changed  time.Time
+	creator, modifier string
+}
+
+// ImportTMX puts translations of TMX into m: from the source language of each unit to every
+// other language of it. Dates and authors of units are kept, user is the author without them;
+// units of m changed later than the ones of TMX are kept.
+func (m *Memory) ImportTMX(r io.Reader, user string) (ImportStats, error) {
+	var stats ImportStats
+	d := xml.NewDecoder(r)
+
+	var srcLang string
+	var tu tuv
+	var tuSrcLang string
+	var variants []tuv
+	var variant *tuv
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("tmx: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "header":
+				srcLang = attr(t, "srclang")
+			case "tu":
+				tu, err = readVariant(t, tuv{})
+				if err != nil {
+					return stats, err
+				}
+				tuSrcLang, variants = attr(t, "srclang"), nil
+			case "tuv":
+				v, err := readVariant(t, tu)
+				if err != nil {
+					return stats, err
+				}
+				if v.lang = attr(t, "lang"); v.lang == "" {
+					return stats, errors.New("tmx: <tuv> without xml:lang")
+				}
+				variants = append(variants, v)
+				variant = &variants[len(variants)-1]
+			case "seg":
+				if variant == nil {
+					return stats, errors.New("tmx: <seg> out of <tuv>")
+				}
+				if variant.text, err = readSeg(d); err != nil {
+					return stats, fmt.Errorf("tmx: %w", err)
+				}
+			case "note", "prop":
+				if err := d.Skip(); err != nil {
+					return stats, fmt.Errorf("tmx: %w", err)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "tuv":
+				variant = nil
+			case "tu":
+				importUnit(m, &stats, variants, tuSrcLang, srcLang, user)
+			}
+		}
+	}
+	return stats, nil
+}
+
+func importUnit(m *Memory, stats *ImportStats, variants []tuv, tuSrcLang, srcLang, user string) {
+	if tuSrcLang == "" {
+		tuSrcLang = srcLang
+	}
+	src := -1
+	for i, v := range variants {
+		if strings.EqualFold(v.lang, tuSrcLang) || tuSrcLang == allLanguages {
+			src = i
+			break
+		}
+	}
+	if src < 0 || len(variants) < 2 {
+		stats.Skipped++
+		return
+	}
+
+	source := variants[src]
+	for i, v := range variants {
+		if i == src {
+			continue
+		}
+		if v.text == "" || source.text == "" {
+			stats.Skipped++
+			continue
+		}
+		u := Unit{
+			SrcLang:   source.lang,
+			TrgLang:   v.lang,
+			Source:    source.text,
+			Target:    v.text,
+			Created:   v.created,
+			CreatedBy: v.creator,
+			Changed:   v.changed,
+			ChangedBy: v.modifier,
+		}
+		if u.CreatedBy == "" {
+			u.CreatedBy = user
+		}
+		if u.ChangedBy == "" {
+			u.ChangedBy = user
+		}
+
+		// a unit without dates is a new one
+		changed := u.Changed
+		if changed.IsZero() {
+			changed = u.Created
+		}
+		old, exists := m.Get(u.SrcLang, u.TrgLang, u.Source)
+		if exists && !changed.IsZero() && changed.Before(old.Changed) {
+			stats.Older++
+			continue
+		}
+
+		switch {
+		case !m.Put(u):
+			stats.Skipped++
+		case exists:
+			stats.Changed++
+		default:
+			stats.Added++
+		}
+	}
+}
+
+// readVariant returns attributes of <tu> or <tuv> over the ones of parent
+func readVariant(e xml.StartElement, parent tuv) (tuv, error) {
+	v := parent
+	for _, a := range []struct {
+		name string
+		t    *time.Time
+		s    *string
+	}{
+		{"creationdate", &v.created, nil},
+		{"changedate", &v.changed, nil},
+		{"creationid", nil, &v.creator},
+		{"changeid", nil, &v.modifier},
+	} {
+		value := attr(e, a.name)
+		switch {
+		case value == "":
+		case a.s != nil:
+			*a.s = value
+		default:
+			t, err := time.Parse(tmxTime, value)
+			if err != nil {
+				return v, fmt.Errorf("tmx: <%s %s>: %w", e.Name.Local, a.name, err)
+			}
+			*a.t = t
+		}
+	}
+	return v, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// readSeg reads the content of <seg> as the text of a segment; codes are numbered by their x,
+// the ones without it get numbers after them in their order
+func readSeg(d *xml.Decoder) (string, error) {
+	var tokens []markdown.SegmentToken
+	// codes without x, by their indexes in tokens
+	numbered := map[int]bool{}
+	// <bpt> tokens by their i, and <ept> tokens of them
+	opened := map[string]int{}
+	closed := map[int]int{}
+	maxX := 0
+
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			tokens = append(tokens, markdown.SegmentToken{Kind: markdown.TokenText, Text: string(t)})
+		case xml.StartElement:
+			token := markdown.SegmentToken{Kind: markdown.TokenStandalone}
+			switch t.Name.Local {
+			case "hi":
+				// its text is the text of the segment
+				depth++
+				continue
+			case "bpt":
+				token.Kind = markdown.TokenOpen
+				opened[attr(t, "i")] = len(tokens)
+			case "ept":
+				i, ok := opened[attr(t, "i")]
+				if !ok {
+					return "", fmt.Errorf("<ept i=%q> without <bpt>", attr(t, "i"))
+				}
+				token.Kind = markdown.TokenClose
+				closed[len(tokens)] = i
+			}
+			if token.Kind != markdown.TokenClose {
+				// ph, it, ut and the like are standalone
+				if x, err := strconv.Atoi(attr(t, "x")); err == nil && x > 0 {
+					token.ID, maxX = x, max(maxX, x)
+				} else {
+					numbered[len(tokens)] = true
+				}
+			}
+			tokens = append(tokens, token)
+			// native codes aren't kept
+			if err := d.Skip(); err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			next := maxX + 1
+			var b strings.Builder
+			for i := range tokens {
+				if numbered[i] {
+					tokens[i].ID = next
+					next++
+				}
+				if open, ok := closed[i]; ok {
+					tokens[i].ID = tokens[open].ID
+				}
+				b.WriteString(tokens[i].String())
+			}
+			return b.String(), nil
+		}
+	}
+}
@@ -0,0 +1,382 @@
+// Package xliff writes segments of markdown documents to XLIFF for CAT tools and reads
+// translations back, see markdown.Segments.
+package xliff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+// Version is an XLIFF version.
+type Version string
+
+const (
+	Version20 Version = "2.0"
+	Version12 Version = "1.2"
+)
+
+const (
+	namespace20 = "urn:oasis:names:tc:xliff:document:2.0"
+	namespace12 = "urn:oasis:names:tc:xliff:document:1.2"
+)
+
+// File is one markdown source to translate.
+type File struct {
+	// Original is the name of the source
+	Original string
+
+	SrcLang string
+	TrgLang string
+
+	// Segments are segments of the source or their sentences, see markdown.SentenceRules.SplitSegments;
+	// sentences of a segment go to one unit of XLIFF 2.0
+	Segments []markdown.Segment
+
+	// Targets are translations of Segments by their IDs
+	Targets map[string]string
+}
+
+// Translations returns translations of segments of the source, for markdown.Translate.
+func (f *File) Translations() map[string]string {
+	return markdown.JoinSentences(f.Segments, f.Targets)
+}
+
+// unitID returns the ID of the segment a sentence is of
+func unitID(id string) string {
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Write writes f as XLIFF of version.
+func Write(w io.Writer, f *File, version Version) error {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+
+	switch version {
+	case Version20:
+		write20(&b, f)
+	case Version12:
+		write12(&b, f)
+	default:
+		return fmt.Errorf("xliff: unknown version %q", version)
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func note(s markdown.Segment) string {
+	if s.Line > 0 {
+		return fmt.Sprintf("%s, line %d", s.Kind, s.Line)
+	}
+	return s.Kind
+}
+
+func write20(b *bytes.Buffer, f *File) {
+	fmt.Fprintf(b, "<xliff xmlns=%q version=%q srcLang=\"%s\"", namespace20, Version20, escape(f.SrcLang))
+	if f.TrgLang != "" {
+		fmt.Fprintf(b, " trgLang=\"%s\"", escape(f.TrgLang))
+	}
+	fmt.Fprintf(b, ">\n  <file id=\"f1\" original=\"%s\">\n", escape(f.Original))
+
+	for i := 0; i < len(f.Segments); {
+		id := unitID(f.Segments[i].ID)
+		j := i + 1
+		for j < len(f.Segments) && unitID(f.Segments[j].ID) == id {
+			j++
+		}
+		segments := f.Segments[i:j]
+		i = j
+
+		fmt.Fprintf(b, "    <unit id=\"%s\">\n", escape(id))
+		fmt.Fprintf(b, "      <notes>\n        <note>%s</note>\n      </notes>\n", escape(note(segments[0])))
+
+		var placeholders []markdown.Placeholder
+		for _, s := range segments {
+			placeholders = append(placeholders, s.Placeholders...)
+		}
+		if len(placeholders) > 0 {
+			b.WriteString("      <originalData>\n")
+			for _, p := range placeholders {
+				fmt.Fprintf(b, "        <data id=\"d%d\">%s</data>\n", p.ID, escape(p.Start))
+				if p.Paired {
+					fmt.Fprintf(b, "        <data id=\"d%de\">%s</data>\n", p.ID, escape(p.End))
+				}
+			}
+			b.WriteString("      </originalData>\n")
+		}
+
+		for n, s := range segments {
+			if len(segments) > 1 {
+				// ids are unique within a unit, placeholders have numbers
+				fmt.Fprintf(b, "      <segment id=\"s%d\">\n", n+1)
+			} else {
+				b.WriteString("      <segment>\n")
+			}
+			fmt.Fprintf(b, "        <source>%s</source>\n", inline20(s.Text))
+			if target, ok := f.Targets[s.ID]; ok {
+				fmt.Fprintf(b, "        <target>%s</target>\n", inline20(target))
+			}
+			b.WriteString("      </segment>\n")
+		}
+		b.WriteString("    </unit>\n")
+	}
+	b.WriteString("  </file>\n</xliff>\n")
+}
+
+// inline20 returns the text of a segment with placeholders as <ph> and <pc>
+func inline20(text string) string {
+	var b strings.Builder
+	for _, t := range markdown.SegmentTokens(text) {
+		switch t.Kind {
+		case markdown.TokenText:
+			b.WriteString(escape(t.Text))
+		case markdown.TokenOpen:
+			fmt.Fprintf(&b, `<pc id="%d" dataRefStart="d%d" dataRefEnd="d%de">`, t.ID, t.ID, t.ID)
+		case markdown.TokenClose:
+			b.WriteString("</pc>")
+		case markdown.TokenStandalone:
+			fmt.Fprintf(&b, `<ph id="%d" dataRef="d%d"/>`, t.ID, t.ID)
+		}
+	}
+	return b.String()
+}
+
+func write12(b *bytes.Buffer, f *File) {
+	fmt.Fprintf(b, "<xliff xmlns=%q version=%q>\n", namespace12, Version12)
+	fmt.Fprintf(b, "  <file original=\"%s\" source-language=\"%s\"", escape(f.Original), escape(f.SrcLang))
+	if f.TrgLang != "" {
+		fmt.Fprintf(b, " target-language=\"%s\"", escape(f.TrgLang))
+	}
+	b.WriteString(" datatype=\"x-markdown\">\n    <body>\n")
+
+	for _, s := range f.Segments {
+		fmt.Fprintf(b, "      <trans-unit id=\"%s\">\n", escape(s.ID))
+		fmt.Fprintf(b, "        <source>%s</source>\n", inline12(s.Text, s.Placeholders))
+		if target, ok := f.Targets[s.ID]; ok {
+			fmt.Fprintf(b, "        <target>%s</target>\n", inline12(target, s.Placeholders))
+		}
+		fmt.Fprintf(b, "        <note>%s</note>\n", escape(note(s)))
+		b.WriteString("      </trans-unit>\n")
+	}
+	b.WriteString("    </body>\n  </file>\n</xliff>\n")
+}
+
+// inline12 returns the text of a segment with placeholders as <ph> with the markup and <g>
+func inline12(text string, placeholders []markdown.Placeholder) string {
+	markup := map[int]string{}
+	for _, p := range placeholders {
+		markup[p.ID] = p.Start
+	}
+
+	var b strings.Builder
+	for _, t := range markdown.SegmentTokens(text) {
+		switch t.Kind {
+		case markdown.TokenText:
+			b.WriteString(escape(t.Text))
+		case markdown.TokenOpen:
+			fmt.Fprintf(&b, `<g id="%d">`, t.ID)
+		case markdown.TokenClose:
+			b.WriteString("</g>")
+		case markdown.TokenStandalone:
+			fmt.Fprintf(&b, `<ph id="%d">%s</ph>`, t.ID, escape(markup[t.ID]))
+		}
+	}
+	return b.String()
+}
+
+// Read reads XLIFF 2.0 or 1.2 written by Write, with targets filled by a CAT tool.
+// Only segments with non-empty targets get into Targets.
+func Read(r io.Reader) (*File, error) {
+	f := &File{Targets: map[string]string{}}
+	d := xml.NewDecoder(r)
+
+	// the unit of XLIFF 2.0 and its segments
+	var unit string
+	var segments []markdown.Segment
+	var targets []string
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xliff: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "xliff":
+				if v := attr(t, "version"); v != string(Version20) && v != string(Version12) {
+					return nil, fmt.Errorf("xliff: unsupported version %q", v)
+				}
+				f.SrcLang, f.TrgLang = attr(t, "srcLang"), attr(t, "trgLang")
+			case "file":
+				f.Original = attr(t, "original")
+				if lang := attr(t, "source-language"); lang != "" {
+					f.SrcLang, f.TrgLang = lang, attr(t, "target-language")
+				}
+			case "unit", "trans-unit":
+				unit, segments, targets = attr(t, "id"), nil, nil
+				if unit == "" {
+					return nil, fmt.Errorf("xliff: %s without id", t.Name.Local)
+				}
+			case "segment":
+				segments = append(segments, markdown.Segment{})
+				targets = append(targets, "")
+			case "source", "target":
+				text, err := readInline(d)
+				if err != nil {
+					return nil, fmt.Errorf("xliff: unit %s: %w", unit, err)
+				}
+				if len(segments) == 0 {
+					// a trans-unit is a segment itself
+					segments = append(segments, markdown.Segment{ID: unit})
+					targets = append(targets, "")
+				}
+				if t.Name.Local == "source" {
+					segments[len(segments)-1].Text = text
+				} else {
+					targets[len(targets)-1] = text
+				}
+			case "notes", "note", "originalData", "seg-source", "alt-trans", "ignorable":
+				if err := d.Skip(); err != nil {
+					return nil, fmt.Errorf("xliff: %w", err)
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "unit" && t.Name.Local != "trans-unit" {
+				continue
+			}
+			for i := range segments {
+				if t.Name.Local == "unit" {
+					segments[i].ID = unit
+					if len(segments) > 1 {
+						segments[i].ID += "/" + strconv.Itoa(i+1)
+					}
+				}
+				f.Segments = append(f.Segments, segments[i])
+				if targets[i] != "" {
+					f.Targets[segments[i].ID] = targets[i]
+				}
+			}
+			segments, targets = nil, nil
+		}
+	}
+	return f, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// readInline reads the content of <source> or <target> as the text of a segment; codes of CAT
+// tools, like <bpt> and <ept> of XLIFF 1.2, become placeholders by their ids, their native codes
+// and unknown elements aren't kept
+func readInline(d *xml.Decoder) (string, error) {
+	var b strings.Builder
+	// <bpt> ids by their rid or id, for <ept>
+	paired := map[string]int{}
+	pairKey := func(e xml.StartElement) string {
+		if rid := attr(e, "rid"); rid != "" {
+			return rid
+		}
+		return attr(e, "id")
+	}
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.WriteString(markdown.SegmentToken{Kind: markdown.TokenText, Text: string(t)}.String())
+		case xml.StartElement:
+			name := t.Name.Local
+			if name == "mrk" {
+				// an annotation, the text is kept
+				text, err := readInline(d)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(text)
+				continue
+			}
+
+			id, err := strconv.Atoi(attr(t, "id"))
+			switch name {
+			case "ph", "x", "pc", "g", "bpt", "it":
+				if err != nil || id <= 0 {
+					return "", fmt.Errorf("<%s> with unknown id %q", name, attr(t, "id"))
+				}
+			}
+
+			token := markdown.SegmentToken{Kind: markdown.TokenStandalone, ID: id}
+			switch name {
+			case "pc", "g":
+				text, err := readInline(d)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(markdown.SegmentToken{Kind: markdown.TokenOpen, ID: id}.String())
+				b.WriteString(text)
+				b.WriteString(markdown.SegmentToken{Kind: markdown.TokenClose, ID: id}.String())
+				continue
+			case "ph", "x":
+			case "bpt":
+				token.Kind = markdown.TokenOpen
+				paired[pairKey(t)] = id
+			case "ept":
+				n, ok := paired[pairKey(t)]
+				if !ok {
+					return "", fmt.Errorf("<ept id=%q> without <bpt>", attr(t, "id"))
+				}
+				token.Kind, token.ID = markdown.TokenClose, n
+			case "it":
+				switch attr(t, "pos") {
+				case "open":
+					token.Kind = markdown.TokenOpen
+				case "close":
+					token.Kind = markdown.TokenClose
+				}
+			default:
+				// like <ut> or <sub>, native codes with no placeholder
+				if err := d.Skip(); err != nil {
+					return "", err
+				}
+				continue
+			}
+			b.WriteString(token.String())
+			// native codes aren't kept
+			if err := d.Skip(); err != nil {
+				return "", err
+			}
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
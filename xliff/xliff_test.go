@@ -0,0 +1,197 @@
+package xliff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"git.catbo.net/muravjov/go2023/markdown"
+)
+
+const source = "# Hello *world*\n\nRun `go test`. It is {fast} & <b>safe</b>.\n"
+
+func segments() []markdown.Segment {
+	return markdown.DefaultSentenceRules().SplitSegments("en", markdown.Segments([]byte(source)))
+}
+
+func TestWrite20(t *testing.T) {
+	segments := segments()
+	f := &File{Original: "a.md", SrcLang: "en", TrgLang: "ru", Segments: segments,
+		Targets: map[string]string{segments[0].ID: "{1}Привет{/1}, мир"}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, f, Version20))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="ru">
+  <file id="f1" original="a.md">
+    <unit id="`+segments[0].ID+`">
+      <notes>
+        <note>Heading, line 1</note>
+      </notes>
+      <originalData>
+        <data id="d1">*</data>
+        <data id="d1e">*</data>
+      </originalData>
+      <segment>
+        <source>Hello <pc id="1" dataRefStart="d1" dataRefEnd="d1e">world</pc></source>
+        <target><pc id="1" dataRefStart="d1" dataRefEnd="d1e">Привет</pc>, мир</target>
+      </segment>
+    </unit>
+    <unit id="`+segments[1].ID[:12]+`">
+      <notes>
+        <note>Paragraph, line 3</note>
+      </notes>
+      <originalData>
+        <data id="d1">`+"`go test`"+`</data>
+        <data id="d2">&lt;b&gt;</data>
+        <data id="d3">&lt;/b&gt;</data>
+      </originalData>
+      <segment id="s1">
+        <source>Run <ph id="1" dataRef="d1"/>.</source>
+      </segment>
+      <segment id="s2">
+        <source>It is {fast} &amp; <ph id="2" dataRef="d2"/>safe<ph id="3" dataRef="d3"/>.</source>
+      </segment>
+    </unit>
+  </file>
+</xliff>
+`, buf.String())
+}
+
+func TestWrite12(t *testing.T) {
+	segments := segments()
+	f := &File{Original: "a.md", SrcLang: "en", TrgLang: "ru", Segments: segments[1:2]}
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, f, Version12))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="a.md" source-language="en" target-language="ru" datatype="x-markdown">
+    <body>
+      <trans-unit id="`+segments[1].ID+`">
+        <source>Run <ph id="1">`+"`go test`"+`</ph>.</source>
+        <note>Paragraph, line 3</note>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`, buf.String())
+
+	assert.Error(t, Write(&buf, f, "3.0"))
+}
+
+func TestWrite20UniqueIDs(t *testing.T) {
+	segments := segments()
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, &File{Original: "a.md", SrcLang: "en", Segments: segments}, Version20))
+
+	// ids of segments and of codes of their sources are unique within a unit, codes of targets
+	// repeat the ones of sources
+	d := xml.NewDecoder(&buf)
+	var ids map[string]bool
+	units := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		e, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch e.Name.Local {
+		case "unit":
+			ids = map[string]bool{}
+			units++
+		case "target":
+			assert.NoError(t, d.Skip())
+		case "segment", "ph", "pc":
+			id := attr(e, "id")
+			if id == "" {
+				continue
+			}
+			assert.False(t, ids[id], "unit %d: repeated id %s", units, id)
+			ids[id] = true
+		}
+	}
+	assert.Equal(t, 2, units)
+}
+
+func TestReadMerge(t *testing.T) {
+	segments := segments()
+	targets := map[string]string{
+		segments[0].ID: "{1}Привет{/1}, мир",
+		segments[2].ID: "Он {{быстрый} и {2/}надёжный{3/}.",
+	}
+	for _, version := range []Version{Version20, Version12} {
+		var buf bytes.Buffer
+		assert.NoError(t, Write(&buf, &File{Original: "a.md", SrcLang: "en", TrgLang: "ru",
+			Segments: segments, Targets: targets}, version))
+
+		f, err := Read(&buf)
+		if !assert.NoError(t, err, version) {
+			continue
+		}
+		assert.Equal(t, "a.md", f.Original)
+		assert.Equal(t, "en", f.SrcLang)
+		assert.Equal(t, "ru", f.TrgLang)
+		assert.Equal(t, targets, f.Targets)
+		if assert.Len(t, f.Segments, len(segments)) {
+			for i, s := range segments {
+				assert.Equal(t, s.ID, f.Segments[i].ID)
+				assert.Equal(t, s.Text, f.Segments[i].Text)
+			}
+		}
+
+		var out bytes.Buffer
+		assert.NoError(t, markdown.Translate([]byte(source), &out, f.Translations(),
+			markdown.WithMarkdownOptions(markdown.WithKeepSource(true))))
+		assert.Equal(t, "# *Привет*, мир\n\nRun `go test`. Он {быстрый} и <b>надёжный</b>.\n", out.String())
+	}
+}
+
+func TestReadCATMarkup(t *testing.T) {
+	f, err := Read(strings.NewReader(`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en">
+  <file id="f1">
+    <unit id="u1">
+      <segment>
+        <source>a <pc id="1">b</pc></source>
+        <target><mrk id="m1" translate="yes">а <pc id="1">б</pc></mrk></target>
+      </segment>
+    </unit>
+    <unit id="u2"><segment><source>c</source><target/></segment></unit>
+    <unit id="u3">
+      <segment><source>d.</source><target>д.</target></segment>
+      <ignorable><source> </source></ignorable>
+      <segment><source>e.</source><target>е.</target></segment>
+    </unit>
+  </file>
+</xliff>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{"u1": "а {1}б{/1}", "u3/1": "д.", "u3/2": "е."}, f.Targets)
+
+	// codes with native ones become placeholders, native codes aren't kept
+	f, err = Read(strings.NewReader(`<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="a.md" source-language="en" target-language="ru">
+    <body>
+      <trans-unit id="u1">
+        <source>a <g id="1">b</g> <ph id="2">` + "`c`" + `</ph><it id="3" pos="open">&lt;b&gt;</it>d</source>
+        <target><bpt id="1">*</bpt>б<ept id="1">*</ept> <ph id="2">` + "`c`" + `</ph><it id="3" pos="open">&lt;b&gt;</it>г<ut>&lt;br&gt;</ut></target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{"u1": "{1}б{/1} {2/}{3}г"}, f.Targets)
+
+	_, err = Read(strings.NewReader(`<xliff version="2.0"><file><unit id="u1"><segment>` +
+		`<source><ph id="x"/></source></segment></unit></file></xliff>`))
+	assert.EqualError(t, err, `xliff: unit u1: <ph> with unknown id "x"`)
+}